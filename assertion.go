@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const maxAssertionBodySize = 10 << 20
+
+type JSONAssertion struct {
+	Path  string `yaml:"path"`
+	Value string `yaml:"value"`
+}
+
+type AssertionConfig struct {
+	BodyMatch    []string          `yaml:"body_match"`
+	BodyNotMatch []string          `yaml:"body_not_match"`
+	Headers      map[string]string `yaml:"headers"`
+	JSON         []JSONAssertion   `yaml:"json"`
+	MinBodySize  int64             `yaml:"min_body_size"`
+	MaxBodySize  int64             `yaml:"max_body_size"`
+	// Compiled by Validate
+	bodyMatch    []*regexp.Regexp
+	bodyNotMatch []*regexp.Regexp
+	headers      map[string]*regexp.Regexp
+	jsonPaths    [][]jsonPathStep
+}
+
+func (a *AssertionConfig) IsEmpty() bool {
+	return a == nil || (len(a.BodyMatch) == 0 && len(a.BodyNotMatch) == 0 &&
+		len(a.Headers) == 0 && len(a.JSON) == 0 && a.MinBodySize == 0 && a.MaxBodySize == 0)
+}
+
+// Validate check sizes and compile patterns and JSONPaths used by Assert.
+func (a *AssertionConfig) Validate() error {
+	if a == nil {
+		return nil
+	}
+	// Body is truncated to maxAssertionBodySize, so larger sizes never fail
+	if a.MinBodySize > maxAssertionBodySize {
+		return fmt.Errorf("Assertion min_body_size should not be greater than %d", maxAssertionBodySize)
+	}
+	if a.MaxBodySize >= maxAssertionBodySize {
+		return fmt.Errorf("Assertion max_body_size should be less than %d", maxAssertionBodySize)
+	}
+	var err error
+	if a.bodyMatch, err = compilePatterns(a.BodyMatch); err != nil {
+		return err
+	}
+	if a.bodyNotMatch, err = compilePatterns(a.BodyNotMatch); err != nil {
+		return err
+	}
+	a.headers = make(map[string]*regexp.Regexp)
+	for name, pattern := range a.Headers {
+		if a.headers[name], err = regexp.Compile(pattern); err != nil {
+			return err
+		}
+	}
+	a.jsonPaths = make([][]jsonPathStep, len(a.JSON))
+	for i, ja := range a.JSON {
+		if a.jsonPaths[i], err = parseJSONPath(ja.Path); err != nil {
+			return fmt.Errorf("Invalid JSONPath %s: %v", ja.Path, err)
+		}
+	}
+	return nil
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	ret := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = re
+	}
+	return ret, nil
+}
+
+// AssertionError is returned when response does not satisfy an assertion,
+// Reason is a short name used as metric label.
+type AssertionError struct {
+	Reason string
+	Msg    string
+}
+
+func (e *AssertionError) Error() string {
+	return fmt.Sprintf("Assertion %s failed: %s", e.Reason, e.Msg)
+}
+
+func newAssertionError(reason, format string, args ...interface{}) *AssertionError {
+	return &AssertionError{
+		Reason: reason,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+// AssertionErrors is returned when response does not satisfy one or more
+// assertions.
+type AssertionErrors []*AssertionError
+
+func (e AssertionErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Reasons returns reasons of failed assertions without duplicates.
+func (e AssertionErrors) Reasons() []string {
+	ret := []string{}
+	seen := make(map[string]bool)
+	for _, err := range e {
+		if !seen[err.Reason] {
+			seen[err.Reason] = true
+			ret = append(ret, err.Reason)
+		}
+	}
+	return ret
+}
+
+// Assert check response header and body, returns AssertionErrors of all
+// failed assertions. Validate must be called before.
+func (a *AssertionConfig) Assert(header http.Header, body []byte) error {
+	failures := AssertionErrors{}
+	size := int64(len(body))
+	if a.MinBodySize > 0 && size < a.MinBodySize {
+		failures = append(failures, newAssertionError("body_size", "body size %d less than %d", size, a.MinBodySize))
+	}
+	if a.MaxBodySize > 0 && size > a.MaxBodySize {
+		failures = append(failures, newAssertionError("body_size", "body size %d greater than %d", size, a.MaxBodySize))
+	}
+	for _, re := range a.bodyMatch {
+		if !re.Match(body) {
+			failures = append(failures, newAssertionError("body_match", "body not match %q", re))
+		}
+	}
+	for _, re := range a.bodyNotMatch {
+		if re.Match(body) {
+			failures = append(failures, newAssertionError("body_not_match", "body match %q", re))
+		}
+	}
+	for name, re := range a.headers {
+		values, ok := header[http.CanonicalHeaderKey(name)]
+		if !ok {
+			failures = append(failures, newAssertionError("header", "header %s not found", name))
+		} else if !re.MatchString(strings.Join(values, ", ")) {
+			failures = append(failures, newAssertionError("header", "header %s not match %q", name, re))
+		}
+	}
+	if len(a.JSON) > 0 {
+		var data interface{}
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&data); err != nil {
+			failures = append(failures, newAssertionError("json", "invalid JSON body: %v", err))
+		} else {
+			for i, ja := range a.JSON {
+				value, err := evalJSONPath(data, a.jsonPaths[i])
+				if err != nil {
+					failures = append(failures, newAssertionError("json", "%s: %v", ja.Path, err))
+				} else if fmt.Sprint(value) != ja.Value {
+					failures = append(failures, newAssertionError("json", "%s is %v, expect %s", ja.Path, value, ja.Value))
+				}
+			}
+		}
+	}
+	if len(failures) > 0 {
+		return failures
+	}
+	return nil
+}
+
+// jsonPathStep is an object key or an array index of JSONPath, Index is -1
+// for object key.
+type jsonPathStep struct {
+	Key   string
+	Index int
+}
+
+// parseJSONPath supports a subset of JSONPath, such as $.data.items[0].name
+// or $['data']['name'].
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSONPath should start with $")
+	}
+	rest := path[1:]
+	ret := []jsonPathStep{}
+	for rest != "" {
+		step := jsonPathStep{Index: -1}
+		switch {
+		case strings.HasPrefix(rest, "['"):
+			end := strings.Index(rest, "']")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed bracket")
+			}
+			step.Key, rest = rest[2:end], rest[end+2:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed bracket")
+			}
+			idx, err := strconv.Atoi(rest[1:end])
+			if err != nil || idx < 0 {
+				return nil, fmt.Errorf("invalid index %q", rest[1:end])
+			}
+			step.Index, rest = idx, rest[end+1:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			step.Key, rest = rest[:end], rest[end:]
+		default:
+			return nil, fmt.Errorf("unexpected %q", rest)
+		}
+		ret = append(ret, step)
+	}
+	return ret, nil
+}
+
+// evalJSONPath returns value of parsed JSONPath in data.
+func evalJSONPath(data interface{}, steps []jsonPathStep) (interface{}, error) {
+	current := data
+	for _, step := range steps {
+		if step.Index >= 0 {
+			list, ok := current.([]interface{})
+			if !ok || step.Index >= len(list) {
+				return nil, fmt.Errorf("index %d not found", step.Index)
+			}
+			current = list[step.Index]
+			continue
+		}
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("key %q not found", step.Key)
+		}
+		current, ok = obj[step.Key]
+		if !ok {
+			return nil, fmt.Errorf("key %q not found", step.Key)
+		}
+	}
+	return current, nil
+}
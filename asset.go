@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	defaultMaxAssets = 50
+	// Assets fetched at the same time of a page
+	assetConcurrency = 8
+)
+
+// AssetConfig enables checking script, stylesheet and image assets of HTML
+// response. Assets on the page host and allowed hosts are checked.
+type AssetConfig struct {
+	AllowHosts []string `yaml:"allow_hosts"`
+	MaxAssets  int      `yaml:"max_assets"`
+}
+
+func (a *AssetConfig) GetMaxAssets() int {
+	if a.MaxAssets <= 0 {
+		return defaultMaxAssets
+	}
+	return a.MaxAssets
+}
+
+// isAllowed returns true if asset is on the page host or allowed hosts, scheme
+// and port are ignored the same as assetTransport does.
+func (a *AssetConfig) isAllowed(asset, page *url.URL) bool {
+	if strings.EqualFold(asset.Hostname(), page.Hostname()) {
+		return true
+	}
+	for _, host := range a.AllowHosts {
+		if strings.EqualFold(asset.Hostname(), host) {
+			return true
+		}
+	}
+	return false
+}
+
+type AssetResult struct {
+	URL        string
+	StatusCode int
+	ErrorMsg   string
+}
+
+// extractAssets returns URLs of script, link and img elements in body, the
+// relative URLs are resolved against page or base element.
+func extractAssets(body []byte, page *url.URL) []*url.URL {
+	ret := []*url.URL{}
+	seen := make(map[string]bool)
+	base := page
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			return ret
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		token := tokenizer.Token()
+		attrs := make(map[string]string)
+		for _, attr := range token.Attr {
+			attrs[attr.Key] = attr.Val
+		}
+		var ref string
+		switch token.Data {
+		case "base":
+			if u, err := page.Parse(attrs["href"]); err == nil && attrs["href"] != "" {
+				base = u
+			}
+			continue
+		case "script", "img":
+			ref = attrs["src"]
+		case "link":
+			switch strings.ToLower(attrs["rel"]) {
+			case "stylesheet", "icon", "shortcut icon", "preload", "modulepreload", "manifest":
+				ref = attrs["href"]
+			}
+		}
+		ref = strings.TrimSpace(ref)
+		if ref == "" || strings.HasPrefix(ref, "data:") {
+			continue
+		}
+		u, err := base.Parse(ref)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		u.Fragment = ""
+		if !seen[u.String()] {
+			seen[u.String()] = true
+			ret = append(ret, u)
+		}
+	}
+}
+
+// assetTransport sends requests of pinned host through pinned transport, so
+// same origin assets are fetched from the same edge address as the page.
+type assetTransport struct {
+	host   string
+	pinned http.RoundTripper
+	other  http.RoundTripper
+}
+
+func (t *assetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.EqualFold(req.URL.Hostname(), t.host) {
+		return t.pinned.RoundTrip(req)
+	}
+	return t.other.RoundTrip(req)
+}
+
+// CloseIdleConnections close idle connections of other transport, pinned one
+// is owned and closed by the page request.
+func (t *assetTransport) CloseIdleConnections() {
+	if tp, ok := t.other.(interface{ CloseIdleConnections() }); ok {
+		tp.CloseIdleConnections()
+	}
+}
+
+// checkAssets fetch allowed assets of page and returns result of each asset.
+func (a *AssetConfig) checkAssets(body []byte, page *url.URL, transport http.RoundTripper) []AssetResult {
+	assets := []*url.URL{}
+	for _, u := range extractAssets(body, page) {
+		if a.isAllowed(u, page) {
+			assets = append(assets, u)
+		}
+		if len(assets) >= a.GetMaxAssets() {
+			break
+		}
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   10 * time.Second,
+	}
+	defer client.CloseIdleConnections()
+	ret := make([]AssetResult, len(assets))
+	sem := make(chan struct{}, assetConcurrency)
+	var wg sync.WaitGroup
+	wg.Add(len(assets))
+	for i, u := range assets {
+		go func(idx int, u string) {
+			sem <- struct{}{}
+			ret[idx] = fetchAsset(client, u)
+			<-sem
+			wg.Done()
+		}(i, u.String())
+	}
+	wg.Wait()
+	return ret
+}
+
+func fetchAsset(client *http.Client, u string) AssetResult {
+	ret := AssetResult{
+		URL: u,
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	req.Header.Set("User-Agent", "domain-exporter")
+	resp, err := client.Do(req)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	defer resp.Body.Close()
+	ret.StatusCode = resp.StatusCode
+	if resp.StatusCode != 200 {
+		ret.ErrorMsg = fmt.Sprintf("Asset status not equals to 200, %v", resp.StatusCode)
+		return ret
+	}
+	if _, err = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxAssertionBodySize)); err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+	}
+	return ret
+}
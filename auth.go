@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Token is refreshed this long before it expires
+const oauth2ExpiryDelta = 30 * time.Second
+
+// AuthConfig is authentication of request, secrets are read from files or
+// environment variables so they are not kept in config file.
+type AuthConfig struct {
+	Type             string   `yaml:"type"`
+	Username         string   `yaml:"username"`
+	PasswordFile     string   `yaml:"password_file"`
+	PasswordEnv      string   `yaml:"password_env"`
+	TokenFile        string   `yaml:"token_file"`
+	TokenEnv         string   `yaml:"token_env"`
+	TokenURL         string   `yaml:"token_url"`
+	ClientID         string   `yaml:"client_id"`
+	ClientSecretFile string   `yaml:"client_secret_file"`
+	ClientSecretEnv  string   `yaml:"client_secret_env"`
+	Scopes           []string `yaml:"scopes"`
+}
+
+func (a *AuthConfig) Validate() error {
+	if a == nil {
+		return nil
+	}
+	switch a.Type {
+	case "basic", "bearer":
+		return nil
+	case "oauth2":
+		if a.TokenURL == "" {
+			return fmt.Errorf("OAuth2 auth requires token_url")
+		}
+		return nil
+	default:
+		return fmt.Errorf("Invalid auth type: %s, should be basic, bearer or oauth2", a.Type)
+	}
+}
+
+// readSecret returns content of file or value of environment variable env,
+// file is read every time so it can be rotated without reload.
+func readSecret(name, file, env string) (string, error) {
+	if file != "" {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	if env != "" {
+		value, ok := os.LookupEnv(env)
+		if !ok {
+			return "", fmt.Errorf("Environment variable %s of %s is not set", env, name)
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("No %s_file or %s_env configured", name, name)
+}
+
+// Authorize set Authorization header of req, dialer is used to fetch OAuth2
+// token. Errors are RequestError with auth or token reason.
+func (a *AuthConfig) Authorize(req *http.Request, dialer Dialer) error {
+	if a == nil {
+		return nil
+	}
+	switch a.Type {
+	case "basic":
+		password, err := readSecret("password", a.PasswordFile, a.PasswordEnv)
+		if err != nil {
+			return newRequestError("auth", err)
+		}
+		req.SetBasicAuth(a.Username, password)
+	case "bearer":
+		token, err := readSecret("token", a.TokenFile, a.TokenEnv)
+		if err != nil {
+			return newRequestError("auth", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case "oauth2":
+		token, err := oauth2Tokens.Get(a, dialer)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// Unauthorized drop cached token when server rejects it.
+func (a *AuthConfig) Unauthorized() {
+	if a != nil && a.Type == "oauth2" {
+		oauth2Tokens.Delete(a)
+	}
+}
+
+type oauth2Token struct {
+	AccessToken string
+	// Zero means token has no expiry and is used until rejected
+	Expiry time.Time
+}
+
+// oauth2TokenEntry holds token of one client, lock is held while fetching so
+// targets sharing a client only fetch once.
+type oauth2TokenEntry struct {
+	token oauth2Token
+	valid bool
+	lock  sync.Mutex
+}
+
+type oauth2TokenCache struct {
+	tokens map[string]*oauth2TokenEntry
+	lock   sync.Mutex
+}
+
+// oauth2Tokens is shared by all request checks so the token is reused
+// across collections.
+var oauth2Tokens = &oauth2TokenCache{
+	tokens: make(map[string]*oauth2TokenEntry),
+}
+
+func (c *oauth2TokenCache) key(a *AuthConfig) string {
+	return fmt.Sprintf("%s|%s|%s", a.TokenURL, a.ClientID, strings.Join(a.Scopes, " "))
+}
+
+// entry returns token entry of a, cache lock is only held for map access so
+// fetching token of one client does not block others.
+func (c *oauth2TokenCache) entry(a *AuthConfig) *oauth2TokenEntry {
+	key := c.key(a)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.tokens[key]
+	if !ok {
+		entry = &oauth2TokenEntry{}
+		c.tokens[key] = entry
+	}
+	return entry
+}
+
+func (c *oauth2TokenCache) Get(a *AuthConfig, dialer Dialer) (string, error) {
+	entry := c.entry(a)
+	entry.lock.Lock()
+	defer entry.lock.Unlock()
+	if entry.valid {
+		if entry.token.Expiry.IsZero() || time.Now().Add(oauth2ExpiryDelta).Before(entry.token.Expiry) {
+			return entry.token.AccessToken, nil
+		}
+	}
+	token, err := fetchOAuth2Token(a, dialer)
+	if err != nil {
+		entry.valid = false
+		return "", newRequestError("token", err)
+	}
+	entry.token = token
+	entry.valid = true
+	return token.AccessToken, nil
+}
+
+func (c *oauth2TokenCache) Delete(a *AuthConfig) {
+	entry := c.entry(a)
+	entry.lock.Lock()
+	defer entry.lock.Unlock()
+	entry.valid = false
+}
+
+// fetchOAuth2Token request token by client credentials grant, see RFC 6749
+// section 4.4.
+func fetchOAuth2Token(a *AuthConfig, dialer Dialer) (oauth2Token, error) {
+	var ret oauth2Token
+	secret, err := readSecret("client_secret", a.ClientSecretFile, a.ClientSecretEnv)
+	if err != nil {
+		return ret, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(a.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.Scopes, " "))
+	}
+	req, err := http.NewRequest("POST", a.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return ret, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(a.ClientID), url.QueryEscape(secret))
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: dialer.DialContext,
+		},
+		Timeout: 10 * time.Second,
+	}
+	defer client.CloseIdleConnections()
+	resp, err := client.Do(req)
+	if err != nil {
+		return ret, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return ret, err
+	}
+	if resp.StatusCode != 200 {
+		return ret, fmt.Errorf("Token endpoint status not equals to 200, %v", resp.StatusCode)
+	}
+	var tr struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err = json.Unmarshal(body, &tr); err != nil {
+		return ret, err
+	}
+	if tr.AccessToken == "" {
+		return ret, fmt.Errorf("Token endpoint returns no access_token")
+	}
+	if tr.TokenType != "" && !strings.EqualFold(tr.TokenType, "bearer") {
+		return ret, fmt.Errorf("Unsupported token type: %s", tr.TokenType)
+	}
+	ret.AccessToken = tr.AccessToken
+	if tr.ExpiresIn > 0 {
+		ret.Expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return ret, nil
+}
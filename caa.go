@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Known certificate issuer organizations and their CAA identifiers.
+var defaultCAAIssuers = map[string][]string{
+	"Let's Encrypt":             {"letsencrypt.org"},
+	"DigiCert Inc":              {"digicert.com", "symantec.com", "geotrust.com", "rapidssl.com", "thawte.com"},
+	"Sectigo Limited":           {"sectigo.com", "comodoca.com", "comodo.com", "usertrust.com"},
+	"ZeroSSL":                   {"sectigo.com"},
+	"GlobalSign nv-sa":          {"globalsign.com"},
+	"Google Trust Services LLC": {"pki.goog"},
+	"Amazon":                    {"amazon.com", "amazontrust.com", "awstrust.com", "amazonaws.com"},
+	"GoDaddy.com, Inc.":         {"godaddy.com", "starfieldtech.com"},
+	"Entrust, Inc.":             {"entrust.net"},
+	"Microsoft Corporation":     {"microsoft.com"},
+	"Buypass AS-983163327":      {"buypass.com"},
+	"SSL Corporation":           {"ssl.com"},
+}
+
+// Property tags defined by RFC 8659 and its extensions.
+var knownCAATags = map[string]bool{
+	"issue":        true,
+	"issuewild":    true,
+	"iodef":        true,
+	"contactemail": true,
+	"contactphone": true,
+	"issuemail":    true,
+	"issuevmc":     true,
+}
+
+type CAARecord struct {
+	Flags uint8
+	Tag   string
+	Value string
+}
+
+type CAAResult struct {
+	Domain       string
+	CNAME        string
+	Issuer       string
+	Status       string
+	ErrorMsg     string
+	Compliant    bool
+	RecordDomain string
+	Allowed      []string
+}
+
+type CAAResults map[string]CAAResult
+
+type CAAChecker struct {
+	Issuers map[string][]string
+}
+
+func NewCAAChecker(issuers map[string][]string) *CAAChecker {
+	merged := make(map[string][]string)
+	for issuer, ids := range defaultCAAIssuers {
+		merged[issuer] = ids
+	}
+	for issuer, ids := range issuers {
+		merged[issuer] = ids
+	}
+	return &CAAChecker{
+		Issuers: merged,
+	}
+}
+
+// Check verify CAA records against certificates that are checked successfully.
+// CAA is not address family specific, so certificates of the same domain and
+// issuer checked over IPv4 and IPv6 are only verified once.
+func (cc *CAAChecker) Check(certs CertResults) CAAResults {
+	ret := make(CAAResults)
+	for _, cert := range certs {
+		if cert.Status != "OK" {
+			continue
+		}
+		key := fmt.Sprintf("%s|%s|%s", cert.Domain, cert.CNAME, cert.Issuer)
+		if _, ok := ret[key]; ok {
+			continue
+		}
+		ret[key] = cc.CheckOneDomain(cert)
+	}
+	return ret
+}
+
+func (cc *CAAChecker) CheckOneDomain(cert CertResult) CAAResult {
+	ret := CAAResult{
+		Domain: cert.Domain,
+		CNAME:  cert.CNAME,
+		Issuer: cert.Issuer,
+		Status: "Error",
+	}
+	domain, records, err := findCAARecords(cert.Domain)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		log.Printf("CAAChecker Error: %s: %v", cert.Domain, err)
+		return ret
+	}
+	ret.Status = "OK"
+	ret.RecordDomain = domain
+	if len(records) == 0 {
+		// No CAA records means any CA is allowed
+		ret.Compliant = true
+		return ret
+	}
+
+	wildcard := isWildcardCovered(cert.Domain, cert.DNSNames)
+	issue := []string{}
+	issueWild := []string{}
+	for _, record := range records {
+		tag := strings.ToLower(record.Tag)
+		if record.Flags&128 != 0 && !knownCAATags[tag] {
+			// Unknown critical property forbids any issuance, see RFC 8659 section 4.5
+			ret.ErrorMsg = fmt.Sprintf("Unknown critical CAA tag %s of %s forbids issuance", record.Tag, domain)
+			log.Printf("CAAChecker Warning: %s: %s", cert.Domain, ret.ErrorMsg)
+			return ret
+		}
+		value := strings.ToLower(strings.TrimSpace(strings.SplitN(record.Value, ";", 2)[0]))
+		switch tag {
+		case "issue":
+			issue = append(issue, value)
+		case "issuewild":
+			issueWild = append(issueWild, value)
+		}
+	}
+	allowed := issue
+	if wildcard && len(issueWild) > 0 {
+		allowed = issueWild
+	}
+	if len(allowed) == 0 {
+		// Only non-issue properties such as iodef exist
+		ret.Compliant = true
+		return ret
+	}
+	ret.Allowed = allowed
+	for _, id := range cc.Issuers[cert.Issuer] {
+		for _, value := range allowed {
+			if value == id {
+				ret.Compliant = true
+				return ret
+			}
+		}
+	}
+	ret.ErrorMsg = fmt.Sprintf("Issuer %q not allowed by CAA of %s: %v", cert.Issuer, domain, allowed)
+	log.Printf("CAAChecker Warning: %s: %s", cert.Domain, ret.ErrorMsg)
+	return ret
+}
+
+// isWildcardCovered returns true when domain is only covered by a wildcard name.
+func isWildcardCovered(domain string, names []string) bool {
+	wildcard := false
+	for _, name := range names {
+		if strings.EqualFold(name, domain) {
+			return false
+		}
+		parts := strings.SplitN(domain, ".", 2)
+		if len(parts) == 2 && strings.EqualFold(name, "*."+parts[1]) {
+			wildcard = true
+		}
+	}
+	return wildcard
+}
+
+// findCAARecords climbs up the domain tree until a CAA record set found, see
+// RFC 8659 section 3.
+func findCAARecords(domain string) (string, []CAARecord, error) {
+	labels := strings.Split(strings.TrimSuffix(domain, "."), ".")
+	for i := range labels {
+		name := strings.Join(labels[i:], ".")
+		records, err := lookupCAA(name)
+		if err != nil {
+			return name, nil, err
+		}
+		if len(records) > 0 {
+			return name, records, nil
+		}
+	}
+	return "", nil, nil
+}
+
+func lookupCAA(name string) ([]CAARecord, error) {
+	resp, err := queryDNS(name, TypeCAA)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.RCode {
+	case dnsmessage.RCodeSuccess, dnsmessage.RCodeNameError:
+	default:
+		return nil, fmt.Errorf("Lookup CAA of %s failed: %v", name, resp.RCode)
+	}
+	ret := []CAARecord{}
+	for _, answer := range resp.Answers {
+		if answer.Header.Type != TypeCAA {
+			continue
+		}
+		body, ok := answer.Body.(*dnsmessage.UnknownResource)
+		if !ok {
+			continue
+		}
+		record, err := parseCAARecord(body.Data)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, record)
+	}
+	return ret, nil
+}
+
+func parseCAARecord(data []byte) (CAARecord, error) {
+	if len(data) < 2 || len(data) < 2+int(data[1]) {
+		return CAARecord{}, fmt.Errorf("Invalid CAA record")
+	}
+	tagLen := int(data[1])
+	return CAARecord{
+		Flags: data[0],
+		Tag:   string(data[2 : 2+tagLen]),
+		Value: string(data[2+tagLen:]),
+	}, nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log"
 	"net"
@@ -13,16 +14,20 @@ import (
 type CertResult struct {
 	Domain     string
 	CNAME      string
+	IPFamily   string
 	Status     string
 	ErrorMsg   string
 	ExpireAt   time.Time
 	ExpireDays int
+	Issuer     string
+	DNSNames   []string
 }
 
 type CertResults map[string]CertResult
 
 type CertificatesChecker struct {
-	Domains []string
+	Domains    []string
+	IPProtocol string
 }
 
 func NewCertificatesChecker(domains []string) *CertificatesChecker {
@@ -37,27 +42,31 @@ func (dc *CertificatesChecker) Check() CertResults {
 		wg   sync.WaitGroup
 		ret  CertResults = make(CertResults)
 	)
-	wg.Add(len(dc.Domains))
+	families := ipFamilies(dc.IPProtocol)
+	wg.Add(len(dc.Domains) * len(families))
 	for _, item := range dc.Domains {
-		go func(domain string) {
-			cr := dc.CheckOneDomain(domain)
-			lock.Lock()
-			ret[domain] = cr
-			lock.Unlock()
-			wg.Done()
-		}(item)
+		for _, family := range families {
+			go func(domain, family string) {
+				cr := dc.CheckOneDomain(domain, family)
+				lock.Lock()
+				ret[familyKey(domain, family)] = cr
+				lock.Unlock()
+				wg.Done()
+			}(item, family)
+		}
 	}
 	wg.Wait()
 	return ret
 }
 
-func (dc *CertificatesChecker) CheckOneDomain(domain string) CertResult {
+func (dc *CertificatesChecker) CheckOneDomain(domain, family string) CertResult {
 	var (
 		ret = CertResult{
-			Domain: domain,
-			Status: "Error",
+			Domain:   domain,
+			IPFamily: family,
+			Status:   "Error",
 		}
-		et    time.Time
+		cert  *x509.Certificate
 		err   error
 		dom   string
 		cname string
@@ -76,7 +85,7 @@ func (dc *CertificatesChecker) CheckOneDomain(domain string) CertResult {
 	}
 
 	for i := 1; i < 4; i++ {
-		et, err = dc.GetExpireTime(dom, cname)
+		cert, err = dc.GetCertificate(dom, cname, family)
 		if err == nil {
 			break
 		}
@@ -88,30 +97,66 @@ func (dc *CertificatesChecker) CheckOneDomain(domain string) CertResult {
 		return ret
 	}
 
-	days := int(et.Sub(time.Now()).Hours() / 24)
+	et := cert.NotAfter
+	days := expireDays(et)
 	log.Println("[INFO] Certificate", dom, "Expire After", days, "Days,", et)
 	ret.Status = "OK"
 	ret.ExpireAt = et
 	ret.ExpireDays = days
+	ret.Issuer = certIssuer(cert)
+	ret.DNSNames = cert.DNSNames
 	return ret
 }
 
 func (dc *CertificatesChecker) GetExpireTime(domain string, cname string) (time.Time, error) {
-	var dialer net.Dialer
-	dialer.Timeout = 5 * time.Second
+	cert, err := dc.GetCertificate(domain, cname, "")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
+
+func (dc *CertificatesChecker) GetCertificate(domain string, cname string, family string) (*x509.Certificate, error) {
 	cfg := &tls.Config{
 		ServerName: domain,
 	}
-	conn, err := tls.DialWithDialer(&dialer, "tcp", fmt.Sprintf("%s:443", cname), cfg)
+	rawConn, err := dialTimeoutWith(getGlobalDialer(), tcpNetwork(family), net.JoinHostPort(cname, "443"), 5*time.Second)
 	if err != nil {
-		return time.Time{}, err
+		return nil, err
 	}
+	conn := tls.Client(rawConn, cfg)
 	defer conn.Close()
-	connStat := conn.ConnectionState()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if err = conn.Handshake(); err != nil {
+		return nil, err
+	}
+	return getLeafCertificate(conn.ConnectionState())
+}
+
+func getLeafCertificate(connStat tls.ConnectionState) (*x509.Certificate, error) {
 	for _, cert := range connStat.PeerCertificates {
 		if !cert.IsCA {
-			return cert.NotAfter, nil
+			return cert, nil
 		}
 	}
-	return time.Time{}, fmt.Errorf("Invalid certificate: no peer certificates")
+	return nil, fmt.Errorf("Invalid certificate: no peer certificates")
+}
+
+func getCertExpireTime(connStat tls.ConnectionState) (time.Time, error) {
+	cert, err := getLeafCertificate(connStat)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
+
+func certIssuer(cert *x509.Certificate) string {
+	if len(cert.Issuer.Organization) > 0 {
+		return cert.Issuer.Organization[0]
+	}
+	return cert.Issuer.CommonName
+}
+
+func expireDays(et time.Time) int {
+	return int(et.Sub(time.Now()).Hours() / 24)
 }
@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"log"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -10,11 +12,27 @@ import (
 
 type Collector struct {
 	config *Config
+	// Labels of each request target, used to delete series of removed ones
+	requestTargets map[string]prometheus.Labels
+	// Labels of domain_request_last_error_info for each request target
+	lastRequestErrors map[string]prometheus.Labels
+	// Last content hash of each request target
+	contentHashes map[string]string
+	// Last registrar and name servers of each whois domain
+	whoisDetails map[string]map[string]string
+	// Whois results of typosquatting permutations
+	typosquatWhois *typosquatWhoisCache
+	lock           sync.Mutex
 }
 
 func NewCollector(cfg *Config) *Collector {
 	return &Collector{
-		config: cfg,
+		config:            cfg,
+		requestTargets:    make(map[string]prometheus.Labels),
+		lastRequestErrors: make(map[string]prometheus.Labels),
+		contentHashes:     make(map[string]string),
+		whoisDetails:      make(map[string]map[string]string),
+		typosquatWhois:    newTyposquatWhoisCache(),
 	}
 }
 
@@ -32,25 +50,103 @@ func decodeStatus(status string) float64 {
 func (c *Collector) collectCertificates() {
 	log.Println("Collect Certificates")
 	checker := NewCertificatesChecker(c.config.GetCertificateDomains())
+	checker.IPProtocol = c.config.GetIPProtocol()
 	results := checker.Check()
 	for _, result := range results {
-		DomainCertificateStatus.With(
+		labels := prometheus.Labels{
+			"domain":     result.Domain,
+			"cname":      result.CNAME,
+			"ip_version": ipVersionLabel(result.IPFamily),
+		}
+		DomainCertificateStatus.With(labels).Set(decodeStatus(result.Status))
+		DomainCertificateExpireDays.With(labels).Set(float64(result.ExpireDays))
+	}
+	caaChecker := NewCAAChecker(c.config.GetCAAIssuers())
+	caaResults := caaChecker.Check(results)
+	// Issuer is a label, clear previous issuers before set new ones
+	DomainCertificateCAACompliant.Reset()
+	for _, result := range caaResults {
+		DomainCertificateCAAStatus.With(
 			prometheus.Labels{"domain": result.Domain, "cname": result.CNAME},
 		).Set(decodeStatus(result.Status))
-		DomainCertificateExpireDays.With(
-			prometheus.Labels{"domain": result.Domain, "cname": result.CNAME},
-		).Set(float64(result.ExpireDays))
+		if result.Status == "OK" {
+			DomainCertificateCAACompliant.With(
+				prometheus.Labels{"domain": result.Domain, "cname": result.CNAME, "issuer": result.Issuer},
+			).Set(boolToFloat(result.Compliant))
+		}
 	}
 	log.Println("Collect Certificates Finish")
 }
 
+// recordWhoisDetails export whois details and count changes of registrar and
+// name servers, empty values are not treated as changes.
+func (c *Collector) recordWhoisDetails(result WhoisResult) {
+	labels := prometheus.Labels{"domain": result.Domain}
+	nameservers := strings.Join(result.NameServers, ",")
+	DomainWhoisInfo.With(prometheus.Labels{
+		"domain":            result.Domain,
+		"registrar":         result.Registrar,
+		"registrar_iana_id": result.RegistrarIANAID,
+		"nameservers":       nameservers,
+		"dnssec":            result.DNSSEC,
+	}).Set(1)
+	if !result.CreatedAt.IsZero() {
+		DomainWhoisCreated.With(labels).Set(float64(result.CreatedAt.Unix()))
+	}
+	if !result.UpdatedAt.IsZero() {
+		DomainWhoisUpdated.With(labels).Set(float64(result.UpdatedAt.Unix()))
+	}
+	details := map[string]string{
+		"registrar":   result.Registrar,
+		"nameservers": nameservers,
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	prev := c.whoisDetails[result.Domain]
+	for field, value := range details {
+		fieldLabels := prometheus.Labels{"domain": result.Domain, "field": field}
+		// Export zero before the first change
+		DomainWhoisChanges.With(fieldLabels).Add(0)
+		if value == "" {
+			details[field] = prev[field]
+			continue
+		}
+		if prev != nil && prev[field] != "" && prev[field] != value {
+			log.Printf("[INFO] Whois %s of %s changed from %q to %q", field, result.Domain, prev[field], value)
+			DomainWhoisChanges.With(fieldLabels).Inc()
+		}
+	}
+	c.whoisDetails[result.Domain] = details
+}
+
 func (c *Collector) collectDomains() {
 	log.Println("Collect Whois Informations")
 	checker := NewWhoisChecker(c.config.GetWhoisDomains())
+	checker.IPProtocol = c.config.GetIPProtocol()
 	results := checker.Check()
+	// Registrar and name servers are labels, clear previous ones before set new ones
+	DomainWhoisInfo.Reset()
 	for _, result := range results {
 		DomainWhoisStatus.With(prometheus.Labels{"domain": result.Domain}).Set(decodeStatus(result.Status))
 		DomainWhoisExpireDays.With(prometheus.Labels{"domain": result.Domain}).Set(float64(result.ExpireDays))
+		if result.Status == "OK" {
+			c.recordWhoisDetails(result)
+		}
+		// Delete statuses of previous check, they are stale when whois fails
+		DomainWhoisEPPStatus.DeletePartialMatch(prometheus.Labels{"domain": result.Domain})
+		DomainWhoisTransferLocked.Delete(prometheus.Labels{"domain": result.Domain})
+		DomainWhoisInGraceOrRedemption.Delete(prometheus.Labels{"domain": result.Domain})
+		// Some registries do not show status, do not report them as unlocked
+		if len(result.EPPStatuses) == 0 {
+			continue
+		}
+		for _, code := range eppStatusCodes {
+			DomainWhoisEPPStatus.With(
+				prometheus.Labels{"domain": result.Domain, "status": code},
+			).Set(boolToFloat(result.HasEPPStatus(code)))
+		}
+		DomainWhoisTransferLocked.With(prometheus.Labels{"domain": result.Domain}).Set(boolToFloat(result.TransferLocked()))
+		DomainWhoisInGraceOrRedemption.With(prometheus.Labels{"domain": result.Domain}).Set(boolToFloat(result.InGraceOrRedemption()))
 	}
 	log.Println("Collect Whois Informations Finish")
 }
@@ -58,46 +154,464 @@ func (c *Collector) collectDomains() {
 func (c *Collector) collectResolves() {
 	log.Println("Collect Resolve Informations")
 	checker := NewResolveChecker(c.config.GetResolveDomains())
+	checker.IPProtocol = c.config.GetIPProtocol()
+	checker.Ping = c.config.GetResolvePing()
 	results := checker.Check()
+	// Address is a label, clear previous addresses before set new ones
+	DomainResolvePingReachable.Reset()
+	DomainResolvePingLoss.Reset()
+	DomainResolvePingRTT.Reset()
 	for _, result := range results {
-		DomainResolveStatus.With(prometheus.Labels{"domain": result.Domain}).Set(decodeStatus(result.Status))
-		DomainResolveIPs.With(prometheus.Labels{"domain": result.Domain}).Set(float64(len(result.IPs)))
+		labels := prometheus.Labels{"domain": result.Domain, "ip_version": ipVersionLabel(result.IPFamily)}
+		DomainResolveStatus.With(labels).Set(decodeStatus(result.Status))
+		DomainResolveIPs.With(labels).Set(float64(len(result.IPs)))
+		for _, pr := range result.Pings {
+			pingLabels := prometheus.Labels{
+				"domain":     result.Domain,
+				"address":    pr.Address,
+				"ip_version": ipVersionLabel(result.IPFamily),
+			}
+			DomainResolvePingReachable.With(pingLabels).Set(decodeStatus(pr.Status))
+			DomainResolvePingLoss.With(pingLabels).Set(pr.Loss())
+			if pr.Received == 0 {
+				continue
+			}
+			rtts := map[string]time.Duration{
+				"min": pr.MinRTT,
+				"avg": pr.AvgRTT,
+				"max": pr.MaxRTT,
+			}
+			for stat, rtt := range rtts {
+				pingLabels["stat"] = stat
+				DomainResolvePingRTT.With(pingLabels).Set(rtt.Seconds())
+			}
+		}
 	}
 	log.Println("Collect Resolve Informations Finish")
 }
 
+// requestLabels returns labels of request target with extra labels.
+func requestLabels(result RequestResult, extra prometheus.Labels) prometheus.Labels {
+	labels := prometheus.Labels{
+		"domain":     result.Domain,
+		"host":       result.Host,
+		"path":       result.Path,
+		"ip_version": ipVersionLabel(result.IPFamily),
+	}
+	for name, value := range extra {
+		labels[name] = value
+	}
+	return labels
+}
+
+// requestKey returns key of request target in collector states.
+func requestKey(result RequestResult) string {
+	return familyKey(fmt.Sprintf("%s @ %s%s", result.Domain, result.Host, result.Path), result.IPFamily)
+}
+
+func (c *Collector) recordContentHash(result RequestResult) {
+	labels := requestLabels(result, nil)
+	DomainRequestContentHash.With(
+		requestLabels(result, prometheus.Labels{"hash": result.ContentHash}),
+	).Set(1)
+	// Export zero before the first change
+	DomainRequestContentChanges.With(labels).Add(0)
+	key := requestKey(result)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	prev, ok := c.contentHashes[key]
+	c.contentHashes[key] = result.ContentHash
+	if ok && prev != result.ContentHash {
+		DomainRequestContentChanges.With(labels).Inc()
+		DomainRequestContentLastChanged.With(labels).Set(float64(time.Now().Unix()))
+	}
+}
+
+func (c *Collector) recordRequestError(result RequestResult) {
+	DomainRequestFailures.With(
+		requestLabels(result, prometheus.Labels{"reason": result.Reason}),
+	).Inc()
+	labels := requestLabels(result, prometheus.Labels{
+		"reason": result.Reason,
+		"status": fmt.Sprintf("%v", result.StatusCode),
+	})
+	key := requestKey(result)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	// Only keep the last error of each target
+	if prev, ok := c.lastRequestErrors[key]; ok {
+		DomainRequestLastError.Delete(prev)
+	}
+	c.lastRequestErrors[key] = labels
+	DomainRequestLastError.With(labels).Set(float64(time.Now().Unix()))
+}
+
+// pruneRequestTargets delete states and series of request targets that are
+// removed from config, they are kept across reloads for current targets.
+func (c *Collector) pruneRequestTargets(results RequestResults) {
+	current := make(map[string]prometheus.Labels)
+	for _, result := range results {
+		current[requestKey(result)] = requestLabels(result, nil)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for key, labels := range c.requestTargets {
+		if _, ok := current[key]; ok {
+			continue
+		}
+		DomainRequestAttempts.DeletePartialMatch(labels)
+		DomainRequestFailures.DeletePartialMatch(labels)
+		DomainRequestLastError.DeletePartialMatch(labels)
+		DomainRequestContentChanges.Delete(labels)
+		DomainRequestContentLastChanged.Delete(labels)
+		DomainRequestHttpsRedirect.Delete(labels)
+		DomainRequestHSTSMaxAge.Delete(labels)
+		DomainRequestHSTSIncludeSubDomains.Delete(labels)
+		DomainRequestHSTSPreload.Delete(labels)
+		DomainRequestSecurityHeader.DeletePartialMatch(labels)
+		DomainRequestSecurityScore.Delete(labels)
+		DomainRequestAssets.Delete(labels)
+		DomainRequestBrokenAssets.Delete(labels)
+		delete(c.lastRequestErrors, key)
+		delete(c.contentHashes, key)
+	}
+	c.requestTargets = current
+}
+
 func (c *Collector) collectRequest() {
 	log.Println("Collect Request Informations")
 	checker := NewRequestChecker(c.config.GetRequestDomains())
+	checker.IPProtocol = c.config.GetIPProtocol()
 	results := checker.Check()
+	c.pruneRequestTargets(results)
+	// Reason, protocol, hash, redirect hop and asset are labels, clear previous
+	// ones before set new ones
+	DomainRequestAssertionFailed.Reset()
+	DomainRequestProtocol.Reset()
+	DomainRequestContentHash.Reset()
+	DomainRequestRedirectHop.Reset()
+	DomainRequestBrokenAsset.Reset()
 	for _, result := range results {
-		DomainRequestStatus.With(
-			prometheus.Labels{
-				"domain": result.Domain,
-				"host":   result.Host,
-				"path":   result.Path,
-			},
-		).Set(decodeStatus(result.Status))
+		DomainRequestStatus.With(requestLabels(result, nil)).Set(decodeStatus(result.Status))
+		DomainRequestAttempts.With(requestLabels(result, nil)).Inc()
 		if result.Status == "Error" {
-			DomainRequestError.With(
-				prometheus.Labels{
-					"domain":  result.Domain,
-					"host":    result.Host,
-					"path":    result.Path,
-					"address": result.Address,
-					"status":  fmt.Sprintf("%v", result.StatusCode),
-				},
-			).Inc()
+			c.recordRequestError(result)
+		}
+		if result.Protocol != "" {
+			DomainRequestProtocol.With(
+				requestLabels(result, prometheus.Labels{"protocol": result.Protocol}),
+			).Set(1)
+		}
+		DomainRequestRedirects.With(requestLabels(result, nil)).Set(float64(len(result.Redirects)))
+		for i, hop := range result.Redirects {
+			DomainRequestRedirectHop.With(
+				requestLabels(result, prometheus.Labels{
+					"hop":      fmt.Sprintf("%v", i),
+					"url":      hop.URL,
+					"status":   fmt.Sprintf("%v", hop.StatusCode),
+					"location": hop.Location,
+				}),
+			).Set(1)
+		}
+		// Delete series of checks that are disabled or not run because of error
+		if result.HttpsRedirect != nil {
+			DomainRequestHttpsRedirect.With(requestLabels(result, nil)).Set(boolToFloat(*result.HttpsRedirect))
+		} else {
+			DomainRequestHttpsRedirect.Delete(requestLabels(result, nil))
+		}
+		if hsts := result.HSTS; hsts != nil {
+			DomainRequestHSTSMaxAge.With(requestLabels(result, nil)).Set(float64(hsts.MaxAge))
+			DomainRequestHSTSIncludeSubDomains.With(requestLabels(result, nil)).Set(boolToFloat(hsts.IncludeSubDomains))
+			DomainRequestHSTSPreload.With(requestLabels(result, nil)).Set(boolToFloat(hsts.Preload))
+		} else {
+			DomainRequestHSTSMaxAge.Delete(requestLabels(result, nil))
+			DomainRequestHSTSIncludeSubDomains.Delete(requestLabels(result, nil))
+			DomainRequestHSTSPreload.Delete(requestLabels(result, nil))
+		}
+		if result.SecurityHeaders != nil {
+			for header, ok := range result.SecurityHeaders {
+				DomainRequestSecurityHeader.With(
+					requestLabels(result, prometheus.Labels{"header": header}),
+				).Set(boolToFloat(ok))
+			}
+			DomainRequestSecurityScore.With(requestLabels(result, nil)).Set(securityScore(result.SecurityHeaders))
+		} else {
+			DomainRequestSecurityHeader.DeletePartialMatch(requestLabels(result, nil))
+			DomainRequestSecurityScore.Delete(requestLabels(result, nil))
+		}
+		if result.Assets != nil {
+			broken := 0
+			for _, asset := range result.Assets {
+				if asset.ErrorMsg == "" {
+					continue
+				}
+				broken++
+				DomainRequestBrokenAsset.With(
+					requestLabels(result, prometheus.Labels{
+						"asset":  asset.URL,
+						"status": fmt.Sprintf("%v", asset.StatusCode),
+					}),
+				).Set(1)
+			}
+			DomainRequestAssets.With(requestLabels(result, nil)).Set(float64(len(result.Assets)))
+			DomainRequestBrokenAssets.With(requestLabels(result, nil)).Set(float64(broken))
+		} else {
+			DomainRequestAssets.Delete(requestLabels(result, nil))
+			DomainRequestBrokenAssets.Delete(requestLabels(result, nil))
+		}
+		if result.ContentHash != "" {
+			c.recordContentHash(result)
+		}
+		if result.Timing.Certificate != nil {
+			DomainRequestCertificateExpireDays.With(requestLabels(result, nil)).Set(float64(result.CertExpireDays))
+		} else {
+			DomainRequestCertificateExpireDays.Delete(requestLabels(result, nil))
+		}
+		phases := map[string]time.Duration{
+			"dns":      result.Timing.DNS,
+			"connect":  result.Timing.Connect,
+			"tls":      result.Timing.TLS,
+			"ttfb":     result.Timing.TTFB,
+			"transfer": result.Timing.Transfer,
+			"total":    result.Timing.Total,
+			"assets":   result.Timing.Assets,
+		}
+		for phase, duration := range phases {
+			DomainRequestDuration.With(
+				requestLabels(result, prometheus.Labels{"phase": phase}),
+			).Set(duration.Seconds())
+		}
+		for _, reason := range result.Assertions {
+			DomainRequestAssertionFailed.With(
+				requestLabels(result, prometheus.Labels{"reason": reason}),
+			).Set(1)
 		}
 	}
 	log.Println("Collect Request Informations Finish")
 }
 
+func (c *Collector) collectMailPolicies() {
+	log.Println("Collect Mail Policy Informations")
+	checker := NewMailPolicyChecker(c.config.GetMailPolicyDomains())
+	checker.IPProtocol = c.config.GetIPProtocol()
+	results := checker.Check()
+	// Mode is a label, clear previous modes before set new ones
+	DomainMailPolicyMode.Reset()
+	for _, result := range results {
+		records := map[string]MailRecordResult{
+			"spf":     result.SPF,
+			"dmarc":   result.DMARC,
+			"mta_sts": result.MTASTS,
+			"tls_rpt": result.TLSRPT,
+		}
+		for record, rr := range records {
+			DomainMailPolicyStatus.With(
+				prometheus.Labels{"domain": result.Domain, "record": record},
+			).Set(decodeStatus(rr.Status))
+			DomainMailPolicyMode.With(
+				prometheus.Labels{"domain": result.Domain, "record": record, "mode": rr.Mode},
+			).Set(1)
+		}
+		DomainSPFLookups.With(prometheus.Labels{"domain": result.Domain}).Set(float64(result.SPFLookups))
+		for selector, rr := range result.DKIM {
+			DomainDKIMStatus.With(
+				prometheus.Labels{"domain": result.Domain, "selector": selector},
+			).Set(decodeStatus(rr.Status))
+		}
+	}
+	log.Println("Collect Mail Policy Informations Finish")
+}
+
+func boolToFloat(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func (c *Collector) collectMX() {
+	log.Println("Collect MX Informations")
+	checker := NewMXChecker(c.config.GetMXDomains())
+	checker.IPProtocol = c.config.GetIPProtocol()
+	results := checker.Check()
+	for _, result := range results {
+		labels := prometheus.Labels{
+			"domain":     result.Domain,
+			"mx":         result.MX,
+			"ip_version": ipVersionLabel(result.IPFamily),
+		}
+		DomainMXStatus.With(labels).Set(decodeStatus(result.Status))
+		DomainMXLatency.With(labels).Set(result.Latency.Seconds())
+		DomainMXStartTLS.With(labels).Set(boolToFloat(result.StartTLS))
+		DomainMXCertificateStatus.With(labels).Set(decodeStatus(result.CertStatus))
+		DomainMXCertificateExpireDays.With(labels).Set(float64(result.CertExpireDays))
+	}
+	log.Println("Collect MX Informations Finish")
+}
+
+func (c *Collector) collectTakeovers() {
+	log.Println("Collect Takeover Informations")
+	checker := NewTakeoverChecker(c.config.GetTakeoverDomains(), c.config.GetTakeoverFingerprints())
+	checker.IPProtocol = c.config.GetIPProtocol()
+	results := checker.Check()
+	// CNAME and reason are labels, clear previous ones before set new ones
+	DomainTakeoverRisk.Reset()
+	for _, result := range results {
+		DomainTakeoverStatus.With(prometheus.Labels{"domain": result.Domain}).Set(decodeStatus(result.Status))
+		if result.Status != "OK" {
+			continue
+		}
+		DomainTakeoverRisk.With(
+			prometheus.Labels{
+				"domain":  result.Domain,
+				"cname":   result.CNAME,
+				"service": result.Service,
+				"reason":  result.Reason,
+			},
+		).Set(boolToFloat(result.Risk))
+	}
+	log.Println("Collect Takeover Informations Finish")
+}
+
+func (c *Collector) collectTyposquats() {
+	log.Println("Collect Typosquat Informations")
+	checker := NewTyposquatChecker(c.config.GetTyposquatDomains())
+	checker.WhoisCache = c.typosquatWhois
+	checker.IPProtocol = c.config.GetIPProtocol()
+	results := checker.Check()
+	// Permutation is a label, clear previous hits before set new ones
+	DomainTyposquatHit.Reset()
+	for _, result := range results {
+		if result.Status != "OK" {
+			continue
+		}
+		DomainTyposquatPermutations.With(prometheus.Labels{"domain": result.Domain}).Set(float64(result.Permutations))
+		DomainTyposquatRegistered.With(prometheus.Labels{"domain": result.Domain}).Set(float64(len(result.Hits)))
+		for _, hit := range result.Hits {
+			DomainTyposquatHit.With(
+				prometheus.Labels{"domain": result.Domain, "permutation": hit.Domain, "kind": hit.Kind},
+			).Set(1)
+		}
+	}
+	log.Println("Collect Typosquat Informations Finish")
+}
+
+func (c *Collector) collectDNSBL() {
+	log.Println("Collect DNSBL Informations")
+	ipLists, domainLists := c.config.GetDNSBLLists()
+	checker := NewDNSBLChecker(c.config.GetDNSBLDomains(), ipLists, domainLists)
+	checker.IPProtocol = c.config.GetIPProtocol()
+	results := checker.Check()
+	// Address is a label, clear previous addresses before set new ones
+	DomainDNSBLStatus.Reset()
+	DomainDNSBLListed.Reset()
+	for _, result := range results {
+		for _, listing := range result.Listings {
+			labels := prometheus.Labels{
+				"domain":  result.Domain,
+				"host":    listing.Host,
+				"address": listing.Address,
+				"list":    listing.List,
+			}
+			DomainDNSBLStatus.With(labels).Set(decodeStatus(listing.Status))
+			if listing.Status == "OK" {
+				DomainDNSBLListed.With(labels).Set(boolToFloat(listing.Listed))
+			}
+		}
+	}
+	log.Println("Collect DNSBL Informations Finish")
+}
+
+func (c *Collector) collectTCP() {
+	log.Println("Collect TCP Informations")
+	checker := NewTCPChecker(c.config.GetTCPChecks())
+	checker.IPProtocol = c.config.GetIPProtocol()
+	results := checker.Check()
+	for _, result := range results {
+		labels := prometheus.Labels{
+			"domain":     result.Domain,
+			"host":       result.Host,
+			"port":       fmt.Sprintf("%v", result.Port),
+			"ip_version": ipVersionLabel(result.IPFamily),
+		}
+		DomainTCPStatus.With(labels).Set(decodeStatus(result.Status))
+		DomainTCPConnectLatency.With(labels).Set(result.ConnectLatency.Seconds())
+		if result.HasCertificate {
+			DomainTCPCertificateExpireDays.With(labels).Set(float64(result.CertExpireDays))
+		}
+	}
+	log.Println("Collect TCP Informations Finish")
+}
+
+func (c *Collector) collectGRPC() {
+	log.Println("Collect gRPC Informations")
+	checker := NewGRPCChecker(c.config.GetGRPCChecks())
+	checker.IPProtocol = c.config.GetIPProtocol()
+	results := checker.Check()
+	// Serving status is a label, clear previous ones before set new ones
+	DomainGRPCServingStatus.Reset()
+	for _, result := range results {
+		labels := prometheus.Labels{
+			"domain":     result.Domain,
+			"host":       result.Host,
+			"port":       fmt.Sprintf("%v", result.Port),
+			"service":    result.Service,
+			"ip_version": ipVersionLabel(result.IPFamily),
+		}
+		DomainGRPCStatus.With(labels).Set(decodeStatus(result.Status))
+		DomainGRPCServing.With(labels).Set(boolToFloat(result.ServingStatus == "SERVING"))
+		DomainGRPCLatency.With(labels).Set(result.Latency.Seconds())
+		if result.ServingStatus != "" {
+			labels["serving_status"] = result.ServingStatus
+			DomainGRPCServingStatus.With(labels).Set(1)
+		}
+	}
+	log.Println("Collect gRPC Informations Finish")
+}
+
+func (c *Collector) collectWebSockets() {
+	log.Println("Collect WebSocket Informations")
+	checker := NewWebSocketChecker(c.config.GetWebSocketChecks())
+	checker.IPProtocol = c.config.GetIPProtocol()
+	results := checker.Check()
+	for _, result := range results {
+		labels := prometheus.Labels{
+			"domain":     result.Domain,
+			"host":       result.Host,
+			"path":       result.Path,
+			"ip_version": ipVersionLabel(result.IPFamily),
+		}
+		DomainWebSocketStatus.With(labels).Set(decodeStatus(result.Status))
+		DomainWebSocketStatusCode.With(labels).Set(float64(result.StatusCode))
+		// Zero means not measured, because the check failed or no message
+		// is configured
+		if result.HandshakeLatency > 0 {
+			DomainWebSocketHandshake.With(labels).Set(result.HandshakeLatency.Seconds())
+		} else {
+			DomainWebSocketHandshake.Delete(labels)
+		}
+		if result.RTT > 0 {
+			DomainWebSocketRTT.With(labels).Set(result.RTT.Seconds())
+		} else {
+			DomainWebSocketRTT.Delete(labels)
+		}
+	}
+	log.Println("Collect WebSocket Informations Finish")
+}
+
 func (c *Collector) CollectOnce() {
 	go c.collectCertificates()
 	go c.collectDomains()
 	go c.collectResolves()
 	go c.collectRequest()
+	go c.collectMailPolicies()
+	go c.collectMX()
+	go c.collectTakeovers()
+	go c.collectTyposquats()
+	go c.collectDNSBL()
+	go c.collectTCP()
+	go c.collectGRPC()
+	go c.collectWebSockets()
 }
 
 func (c *Collector) Start() {
@@ -1,7 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -9,30 +21,306 @@ import (
 )
 
 type RequestConfig struct {
-	Host    string   `yaml:"host"`
-	Domains []string `yaml:"domains"`
-	Path    string   `yaml:"path"`
-	Https   bool     `yaml:"https"`
+	Host               string             `yaml:"host"`
+	Domains            []string           `yaml:"domains"`
+	Path               string             `yaml:"path"`
+	Https              bool               `yaml:"https"`
+	Method             string             `yaml:"method"`
+	Headers            map[string]string  `yaml:"headers"`
+	Body               string             `yaml:"body"`
+	BodyFile           string             `yaml:"body_file"`
+	ExpectStatus       []string           `yaml:"expect_status"`
+	FollowRedirects    *bool              `yaml:"follow_redirects"`
+	Assertions         *AssertionConfig   `yaml:"assertions"`
+	Auth               *AuthConfig        `yaml:"auth"`
+	ContentHash        *ContentHashConfig `yaml:"content_hash"`
+	Redirect           *RedirectConfig    `yaml:"redirect"`
+	SecurityHeaders    bool               `yaml:"security_headers"`
+	Assets             *AssetConfig       `yaml:"assets"`
+	InsecureSkipVerify bool               `yaml:"insecure_skip_verify"`
+	CAFile             string             `yaml:"ca_file"`
+	HttpVersion        string             `yaml:"http_version"`
+	Http3Discovery     string             `yaml:"http3_discovery"`
+	Proxy              string             `yaml:"proxy"`
+	IPProtocol         string             `yaml:"ip_protocol"`
+}
+
+// GetTLSConfig returns TLS config for request, server name is left empty so
+// the SNI name is the host of request URL.
+func (r *RequestConfig) GetTLSConfig() (*tls.Config, error) {
+	return newTLSConfig(r.InsecureSkipVerify, r.CAFile)
+}
+
+// newTLSConfig returns TLS config verify certificate by CA certificates in
+// caFile, or system ones when caFile is empty.
+func newTLSConfig(insecureSkipVerify bool, caFile string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+	if caFile != "" {
+		data, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("No certificate found in %s", caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// methodRe matches token of request method, see RFC 9110 section 9.1.
+var methodRe = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// Validate check method, expected status codes and HTTP version of request.
+func (r *RequestConfig) Validate() error {
+	if r.Method != "" && !methodRe.MatchString(r.Method) {
+		return fmt.Errorf("Invalid request method: %s", r.Method)
+	}
+	for _, expect := range r.ExpectStatus {
+		if _, _, err := parseExpectStatus(expect); err != nil {
+			return err
+		}
+	}
+	switch r.HttpVersion {
+	case "", "1.1":
+	case "2", "3":
+		if !r.Https {
+			return fmt.Errorf("HTTP/%s requires https", r.HttpVersion)
+		}
+	default:
+		return fmt.Errorf("Invalid http_version: %s, should be 1.1, 2 or 3", r.HttpVersion)
+	}
+	switch r.Http3Discovery {
+	case "", "direct", "alt-svc":
+	default:
+		return fmt.Errorf("Invalid http3_discovery: %s, should be direct or alt-svc", r.Http3Discovery)
+	}
+	return nil
+}
+
+func (r *RequestConfig) GetMethod() string {
+	if r.Method == "" {
+		return "GET"
+	}
+	return strings.ToUpper(r.Method)
+}
+
+func (r *RequestConfig) GetExpectStatus() []string {
+	if len(r.ExpectStatus) == 0 {
+		return []string{"200"}
+	}
+	return r.ExpectStatus
+}
+
+func (r *RequestConfig) IsFollowRedirects() bool {
+	return r.FollowRedirects == nil || *r.FollowRedirects
+}
+
+// GetBody returns request body, body_file is read on every request so it
+// can be changed without reload.
+func (r *RequestConfig) GetBody() (io.Reader, error) {
+	if r.BodyFile != "" {
+		data, err := ioutil.ReadFile(r.BodyFile)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+	if r.Body != "" {
+		return strings.NewReader(r.Body), nil
+	}
+	return nil, nil
+}
+
+// ContentHashConfig enables hashing response body of request, matches of
+// normalize regexes are replaced before hashing so dynamic parts such as
+// timestamps or CSRF tokens do not change the hash.
+type ContentHashConfig struct {
+	Normalize []string `yaml:"normalize"`
+	// Compiled by Validate
+	normalize []*regexp.Regexp
+}
+
+// Validate compile normalize regexes used by Hash.
+func (c *ContentHashConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	var err error
+	c.normalize, err = compilePatterns(c.Normalize)
+	return err
+}
+
+// Hash returns hex encoded SHA-256 of normalized body. Validate must be
+// called before.
+func (c *ContentHashConfig) Hash(body []byte) string {
+	for _, re := range c.normalize {
+		body = re.ReplaceAll(body, nil)
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+type MailPolicyConfig struct {
+	Domain        string   `yaml:"domain"`
+	DKIMSelectors []string `yaml:"dkim_selectors"`
+}
+
+type TyposquatConfig struct {
+	Domain     string   `yaml:"domain"`
+	TLDs       []string `yaml:"tlds"`
+	Whois      bool     `yaml:"whois"`
+	WhoisLimit int      `yaml:"whois_limit"`
+}
+
+func (t *TyposquatConfig) GetWhoisLimit() int {
+	if t.WhoisLimit <= 0 {
+		return defaultTyposquatWhoisLimit
+	}
+	return t.WhoisLimit
+}
+
+type TCPConfig struct {
+	Host               string   `yaml:"host"`
+	Port               int      `yaml:"port"`
+	Domains            []string `yaml:"domains"`
+	TLS                bool     `yaml:"tls"`
+	InsecureSkipVerify bool     `yaml:"insecure_skip_verify"`
+	Send               string   `yaml:"send"`
+	Expect             string   `yaml:"expect"`
+	Proxy              string   `yaml:"proxy"`
+	IPProtocol         string   `yaml:"ip_protocol"`
+}
+
+type GRPCConfig struct {
+	Host               string   `yaml:"host"`
+	Port               int      `yaml:"port"`
+	Domains            []string `yaml:"domains"`
+	Service            string   `yaml:"service"`
+	TLS                bool     `yaml:"tls"`
+	InsecureSkipVerify bool     `yaml:"insecure_skip_verify"`
+	CAFile             string   `yaml:"ca_file"`
+	ServerName         string   `yaml:"server_name"`
+	Authority          string   `yaml:"authority"`
+	Proxy              string   `yaml:"proxy"`
+	IPProtocol         string   `yaml:"ip_protocol"`
+}
+
+// GetTLSConfig returns TLS config of gRPC check, server name is host when
+// server_name is not set.
+func (g *GRPCConfig) GetTLSConfig() (*tls.Config, error) {
+	cfg, err := newTLSConfig(g.InsecureSkipVerify, g.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ServerName = g.ServerName
+	if cfg.ServerName == "" {
+		cfg.ServerName = g.Host
+	}
+	cfg.NextProtos = []string{"h2"}
+	return cfg, nil
+}
+
+// GetAuthority returns :authority of gRPC request, default is host:port.
+func (g *GRPCConfig) GetAuthority() string {
+	if g.Authority != "" {
+		return g.Authority
+	}
+	return net.JoinHostPort(g.Host, strconv.Itoa(g.Port))
+}
+
+type WebSocketConfig struct {
+	Host               string            `yaml:"host"`
+	Path               string            `yaml:"path"`
+	TLS                bool              `yaml:"tls"`
+	Domains            []string          `yaml:"domains"`
+	Headers            map[string]string `yaml:"headers"`
+	Send               string            `yaml:"send"`
+	Expect             string            `yaml:"expect"`
+	InsecureSkipVerify bool              `yaml:"insecure_skip_verify"`
+	CAFile             string            `yaml:"ca_file"`
+	Proxy              string            `yaml:"proxy"`
+	IPProtocol         string            `yaml:"ip_protocol"`
+}
+
+// GetPort returns port in host, or default port of ws and wss.
+func (w *WebSocketConfig) GetPort() string {
+	if _, port, err := net.SplitHostPort(w.Host); err == nil {
+		return port
+	}
+	if w.TLS {
+		return "443"
+	}
+	return "80"
+}
+
+type PingConfig struct {
+	Count   int `yaml:"count"`
+	Timeout int `yaml:"timeout"`
+}
+
+func (p *PingConfig) GetCount() int {
+	if p.Count <= 0 {
+		return defaultPingCount
+	}
+	return p.Count
+}
+
+func (p *PingConfig) GetTimeout() time.Duration {
+	if p.Timeout <= 0 {
+		return time.Second * defaultPingTimeout
+	}
+	return time.Second * time.Duration(p.Timeout)
 }
 
 type Config struct {
-	fname              string
-	CollectDuration    int             `yaml:"collect_duration"`
-	CertificateDomains []string        `yaml:"certificate_domains"`
-	WhoisDomains       []string        `yaml:"whois_domains"`
-	ResolveDomains     []string        `yaml:"resolve_domains"`
-	RequestDomains     []RequestConfig `yaml:"request_domains"`
-	lock               sync.RWMutex
+	fname                string
+	CollectDuration      int                   `yaml:"collect_duration"`
+	Proxy                string                `yaml:"proxy"`
+	IPProtocol           string                `yaml:"ip_protocol"`
+	CertificateDomains   []string              `yaml:"certificate_domains"`
+	WhoisDomains         []string              `yaml:"whois_domains"`
+	ResolveDomains       []string              `yaml:"resolve_domains"`
+	ResolvePing          *PingConfig           `yaml:"resolve_ping"`
+	RequestDomains       []RequestConfig       `yaml:"request_domains"`
+	MailPolicyDomains    []MailPolicyConfig    `yaml:"mail_policy_domains"`
+	MXDomains            []string              `yaml:"mx_domains"`
+	CAAIssuers           map[string][]string   `yaml:"caa_issuers"`
+	TakeoverDomains      []string              `yaml:"takeover_domains"`
+	TakeoverFingerprints []TakeoverFingerprint `yaml:"takeover_fingerprints"`
+	TyposquatDomains     []TyposquatConfig     `yaml:"typosquat_domains"`
+	DNSBLDomains         []string              `yaml:"dnsbl_domains"`
+	DNSBLIPLists         []string              `yaml:"dnsbl_ip_lists"`
+	DNSBLDomainLists     []string              `yaml:"dnsbl_domain_lists"`
+	TCPChecks            []TCPConfig           `yaml:"tcp_checks"`
+	GRPCChecks           []GRPCConfig          `yaml:"grpc_checks"`
+	WebSocketChecks      []WebSocketConfig     `yaml:"websocket_checks"`
+	lock                 sync.RWMutex
 }
 
 func NewConfig(fname string) (*Config, error) {
 	cfg := &Config{
-		fname:              fname,
-		CollectDuration:    3600,
-		CertificateDomains: []string{},
-		WhoisDomains:       []string{},
-		ResolveDomains:     []string{},
-		RequestDomains:     []RequestConfig{},
+		fname:                fname,
+		CollectDuration:      3600,
+		CertificateDomains:   []string{},
+		WhoisDomains:         []string{},
+		ResolveDomains:       []string{},
+		RequestDomains:       []RequestConfig{},
+		MailPolicyDomains:    []MailPolicyConfig{},
+		MXDomains:            []string{},
+		CAAIssuers:           map[string][]string{},
+		TakeoverDomains:      []string{},
+		TakeoverFingerprints: []TakeoverFingerprint{},
+		TyposquatDomains:     []TyposquatConfig{},
+		DNSBLDomains:         []string{},
+		DNSBLIPLists:         []string{},
+		DNSBLDomainLists:     []string{},
+		TCPChecks:            []TCPConfig{},
+		GRPCChecks:           []GRPCConfig{},
+		WebSocketChecks:      []WebSocketConfig{},
 	}
 	err := cfg.Reload()
 	return cfg, err
@@ -50,18 +338,109 @@ func (c *Config) Reload() error {
 	if err != nil {
 		return err
 	}
+	dialer, err := NewDialer(cfg.Proxy)
+	if err != nil {
+		return err
+	}
+	if err = validateIPProtocol(cfg.IPProtocol); err != nil {
+		return err
+	}
+	for _, rcfg := range cfg.RequestDomains {
+		if err = rcfg.Validate(); err != nil {
+			return err
+		}
+		if err = validateProxy(rcfg.Proxy); err != nil {
+			return err
+		}
+		// QUIC is UDP based and cannot be proxied
+		if rcfg.HttpVersion == "3" && isProxied(cfg.Proxy, rcfg.Proxy) {
+			return fmt.Errorf("HTTP/3 request of %s cannot use proxy, set proxy to direct", rcfg.Host)
+		}
+		if err = validateIPProtocol(rcfg.IPProtocol); err != nil {
+			return err
+		}
+		if err = rcfg.Auth.Validate(); err != nil {
+			return err
+		}
+		if err = rcfg.Assertions.Validate(); err != nil {
+			return err
+		}
+		if err = rcfg.ContentHash.Validate(); err != nil {
+			return err
+		}
+		if err = rcfg.Redirect.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, fp := range cfg.TakeoverFingerprints {
+		if _, err = regexp.Compile(fp.Pattern); err != nil {
+			return fmt.Errorf("Invalid takeover fingerprint pattern of %s: %v", fp.Service, err)
+		}
+	}
+	for _, tcfg := range cfg.TCPChecks {
+		if err = validateIPProtocol(tcfg.IPProtocol); err != nil {
+			return err
+		}
+		if err = validateProxy(tcfg.Proxy); err != nil {
+			return err
+		}
+		if _, err = regexp.Compile(tcfg.Expect); err != nil {
+			return err
+		}
+	}
+	for _, gcfg := range cfg.GRPCChecks {
+		if err = validateIPProtocol(gcfg.IPProtocol); err != nil {
+			return err
+		}
+		if err = validateProxy(gcfg.Proxy); err != nil {
+			return err
+		}
+	}
+	for _, wcfg := range cfg.WebSocketChecks {
+		if err = validateIPProtocol(wcfg.IPProtocol); err != nil {
+			return err
+		}
+		if err = validateProxy(wcfg.Proxy); err != nil {
+			return err
+		}
+		if _, err = regexp.Compile(wcfg.Expect); err != nil {
+			return err
+		}
+	}
+	setGlobalDialer(dialer)
 	c.lock.Lock()
 	if cfg.CollectDuration >= 60 {
 		c.CollectDuration = cfg.CollectDuration
 	}
+	c.Proxy = cfg.Proxy
+	c.IPProtocol = cfg.IPProtocol
 	c.CertificateDomains = cfg.CertificateDomains
 	c.WhoisDomains = cfg.WhoisDomains
 	c.ResolveDomains = cfg.ResolveDomains
+	c.ResolvePing = cfg.ResolvePing
 	c.RequestDomains = cfg.RequestDomains
+	c.MailPolicyDomains = cfg.MailPolicyDomains
+	c.MXDomains = cfg.MXDomains
+	c.CAAIssuers = cfg.CAAIssuers
+	c.TakeoverDomains = cfg.TakeoverDomains
+	c.TakeoverFingerprints = cfg.TakeoverFingerprints
+	c.TyposquatDomains = cfg.TyposquatDomains
+	c.DNSBLDomains = cfg.DNSBLDomains
+	c.DNSBLIPLists = cfg.DNSBLIPLists
+	c.DNSBLDomainLists = cfg.DNSBLDomainLists
+	c.TCPChecks = cfg.TCPChecks
+	c.GRPCChecks = cfg.GRPCChecks
+	c.WebSocketChecks = cfg.WebSocketChecks
 	c.lock.Unlock()
 	return nil
 }
 
+func (c *Config) GetIPProtocol() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.IPProtocol
+}
+
 func (c *Config) GetCertificateDomains() []string {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
@@ -80,12 +459,86 @@ func (c *Config) GetResolveDomains() []string {
 	return c.ResolveDomains
 }
 
+// GetResolvePing returns ping setting of resolved addresses, nil means ping
+// is disabled.
+func (c *Config) GetResolvePing() *PingConfig {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.ResolvePing
+}
+
 func (c *Config) GetRequestDomains() []RequestConfig {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 	return c.RequestDomains
 }
 
+func (c *Config) GetMailPolicyDomains() []MailPolicyConfig {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.MailPolicyDomains
+}
+
+func (c *Config) GetMXDomains() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.MXDomains
+}
+
+func (c *Config) GetCAAIssuers() map[string][]string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.CAAIssuers
+}
+
+func (c *Config) GetTakeoverDomains() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.TakeoverDomains
+}
+
+func (c *Config) GetTakeoverFingerprints() []TakeoverFingerprint {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.TakeoverFingerprints
+}
+
+func (c *Config) GetTyposquatDomains() []TyposquatConfig {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.TyposquatDomains
+}
+
+func (c *Config) GetDNSBLDomains() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.DNSBLDomains
+}
+
+func (c *Config) GetDNSBLLists() ([]string, []string) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.DNSBLIPLists, c.DNSBLDomainLists
+}
+
+func (c *Config) GetTCPChecks() []TCPConfig {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.TCPChecks
+}
+
+func (c *Config) GetGRPCChecks() []GRPCConfig {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.GRPCChecks
+}
+
+func (c *Config) GetWebSocketChecks() []WebSocketConfig {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.WebSocketChecks
+}
+
 func (c *Config) GetDuration() time.Duration {
 	return time.Second * time.Duration(c.CollectDuration)
 }
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTestServer listen on loopback and handle each connection with handle.
+func startTestServer(t *testing.T, handle func(net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func startEchoServer(t *testing.T) string {
+	return startTestServer(t, func(conn net.Conn) {
+		io.Copy(conn, conn)
+	})
+}
+
+// pipeConn copy data between proxy client and target until either is closed.
+func pipeConn(client net.Conn, clientReader io.Reader, target net.Conn) {
+	go io.Copy(target, clientReader)
+	io.Copy(client, target)
+}
+
+// startConnectProxy start HTTP proxy that requires auth (user:password) when
+// it is not empty. Response of CONNECT is status when it is not empty,
+// otherwise tunnel is established and extra is sent with the 200 response.
+func startConnectProxy(t *testing.T, auth, status, extra string) string {
+	return startTestServer(t, func(conn net.Conn) {
+		br := bufio.NewReader(conn)
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			return
+		}
+		if req.Method != "CONNECT" {
+			io.WriteString(conn, "HTTP/1.1 405 Method Not Allowed\r\n\r\n")
+			return
+		}
+		if auth != "" && req.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)) {
+			io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+			return
+		}
+		if status != "" {
+			io.WriteString(conn, "HTTP/1.1 "+status+"\r\n\r\n")
+			return
+		}
+		target, err := net.Dial("tcp", req.Host)
+		if err != nil {
+			io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+			return
+		}
+		defer target.Close()
+		// Response and extra data are sent in one write so they arrive together
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n"+extra)
+		pipeConn(conn, br, target)
+	})
+}
+
+// startSOCKS5Proxy start SOCKS5 proxy that requires username and password
+// auth (RFC 1929) when user is not empty.
+func startSOCKS5Proxy(t *testing.T, user, password string) string {
+	return startTestServer(t, func(conn net.Conn) {
+		br := bufio.NewReader(conn)
+		// VER NMETHODS METHODS
+		head := make([]byte, 2)
+		if _, err := io.ReadFull(br, head); err != nil || head[0] != 5 {
+			return
+		}
+		if _, err := io.ReadFull(br, make([]byte, head[1])); err != nil {
+			return
+		}
+		if user == "" {
+			conn.Write([]byte{5, 0})
+		} else {
+			conn.Write([]byte{5, 2})
+			// VER ULEN UNAME PLEN PASSWD
+			if _, err := br.ReadByte(); err != nil {
+				return
+			}
+			uname, err := readSOCKS5String(br)
+			if err != nil {
+				return
+			}
+			passwd, err := readSOCKS5String(br)
+			if err != nil {
+				return
+			}
+			if uname != user || passwd != password {
+				conn.Write([]byte{1, 1})
+				return
+			}
+			conn.Write([]byte{1, 0})
+		}
+		// VER CMD RSV ATYP DST.ADDR DST.PORT
+		req := make([]byte, 4)
+		if _, err := io.ReadFull(br, req); err != nil || req[1] != 1 {
+			return
+		}
+		var host string
+		switch req[3] {
+		case 1, 4:
+			ip := make([]byte, 4)
+			if req[3] == 4 {
+				ip = make([]byte, 16)
+			}
+			if _, err := io.ReadFull(br, ip); err != nil {
+				return
+			}
+			host = net.IP(ip).String()
+		case 3:
+			name, err := readSOCKS5String(br)
+			if err != nil {
+				return
+			}
+			host = name
+		default:
+			return
+		}
+		port := make([]byte, 2)
+		if _, err := io.ReadFull(br, port); err != nil {
+			return
+		}
+		target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+		if err != nil {
+			// Host unreachable
+			conn.Write([]byte{5, 4, 0, 1, 0, 0, 0, 0, 0, 0})
+			return
+		}
+		defer target.Close()
+		conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+		pipeConn(conn, br, target)
+	})
+}
+
+func readSOCKS5String(br *bufio.Reader) (string, error) {
+	size, err := br.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, size)
+	if _, err = io.ReadFull(br, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// dialProxy dial addr through proxyURL.
+func dialProxy(proxyURL, addr string) (net.Conn, error) {
+	dialer, err := NewDialer(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	return dialTimeoutWith(dialer, "tcp", addr, 5*time.Second)
+}
+
+// assertEcho check data is echoed back through conn.
+func assertEcho(t *testing.T, conn net.Conn) {
+	t.Helper()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.WriteString(conn, "ping"); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "ping" {
+		t.Fatalf("Expect ping echoed, got %q", buf)
+	}
+}
+
+func TestNewDialer(t *testing.T) {
+	for _, proxyURL := range []string{"", "direct"} {
+		dialer, err := NewDialer(proxyURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := dialer.(*net.Dialer); !ok {
+			t.Errorf("Expect direct dialer for %q, got %T", proxyURL, dialer)
+		}
+	}
+	if _, err := NewDialer("ftp://127.0.0.1:21"); err == nil {
+		t.Error("Expect error of unsupported proxy scheme")
+	}
+}
+
+func TestIsProxied(t *testing.T) {
+	for _, c := range []struct {
+		global, proxy string
+		proxied       bool
+	}{
+		{"", "", false},
+		{"socks5://127.0.0.1:1080", "", true},
+		{"socks5://127.0.0.1:1080", "direct", false},
+		{"", "http://127.0.0.1:3128", true},
+	} {
+		if isProxied(c.global, c.proxy) != c.proxied {
+			t.Errorf("Expect proxied %v of global %q and proxy %q", c.proxied, c.global, c.proxy)
+		}
+	}
+}
+
+func TestHTTPConnectDialer(t *testing.T) {
+	echo := startEchoServer(t)
+	proxyAddr := startConnectProxy(t, "user:secret", "", "")
+	conn, err := dialProxy("http://user:secret@"+proxyAddr, echo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	assertEcho(t, conn)
+}
+
+func TestHTTPConnectDialerAuthFailure(t *testing.T) {
+	echo := startEchoServer(t)
+	proxyAddr := startConnectProxy(t, "user:secret", "", "")
+	for _, proxyURL := range []string{"http://" + proxyAddr, "http://user:wrong@" + proxyAddr} {
+		conn, err := dialProxy(proxyURL, echo)
+		if err == nil {
+			conn.Close()
+			t.Fatalf("Expect auth error of %s", proxyURL)
+		}
+		if !strings.Contains(err.Error(), "407") {
+			t.Errorf("Expect 407 in error, got %v", err)
+		}
+	}
+}
+
+func TestHTTPConnectDialerStatus(t *testing.T) {
+	echo := startEchoServer(t)
+	for _, status := range []string{"403 Forbidden", "502 Bad Gateway", "301 Moved Permanently"} {
+		proxyAddr := startConnectProxy(t, "", status, "")
+		conn, err := dialProxy("http://"+proxyAddr, echo)
+		if err == nil {
+			conn.Close()
+			t.Fatalf("Expect error of CONNECT status %s", status)
+		}
+		if !strings.Contains(err.Error(), status) {
+			t.Errorf("Expect %s in error, got %v", status, err)
+		}
+	}
+}
+
+func TestHTTPConnectDialerBufferedData(t *testing.T) {
+	echo := startEchoServer(t)
+	proxyAddr := startConnectProxy(t, "", "", "banner")
+	conn, err := dialProxy("http://"+proxyAddr, echo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 6)
+	if _, err = io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "banner" {
+		t.Fatalf("Expect data sent after CONNECT response, got %q", buf)
+	}
+	assertEcho(t, conn)
+}
+
+func TestSOCKS5Dialer(t *testing.T) {
+	echo := startEchoServer(t)
+	for _, proxyURL := range []string{
+		"socks5://" + startSOCKS5Proxy(t, "", ""),
+		"socks5://user:secret@" + startSOCKS5Proxy(t, "user", "secret"),
+		"socks5h://user:secret@" + startSOCKS5Proxy(t, "user", "secret"),
+	} {
+		conn, err := dialProxy(proxyURL, echo)
+		if err != nil {
+			t.Fatalf("Dial through %s failed: %v", proxyURL, err)
+		}
+		assertEcho(t, conn)
+		conn.Close()
+	}
+}
+
+func TestSOCKS5DialerAuthFailure(t *testing.T) {
+	echo := startEchoServer(t)
+	proxyAddr := startSOCKS5Proxy(t, "user", "secret")
+	conn, err := dialProxy("socks5://user:wrong@"+proxyAddr, echo)
+	if err == nil {
+		conn.Close()
+		t.Fatal("Expect SOCKS5 auth error")
+	}
+}
+
+func TestSOCKS5DialerUnreachable(t *testing.T) {
+	// Listener is closed so the address refuses connections
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	conn, err := dialProxy("socks5://"+startSOCKS5Proxy(t, "", ""), addr)
+	if err == nil {
+		conn.Close()
+		t.Fatal("Expect error of unreachable target")
+	}
+}
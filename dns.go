@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// TypeCAA is not defined in dnsmessage package.
+const TypeCAA dnsmessage.Type = 257
+
+// dnsServer return first name server in /etc/resolv.conf
+func dnsServer() string {
+	file, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return "127.0.0.1:53"
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return net.JoinHostPort(fields[1], "53")
+		}
+	}
+	return "127.0.0.1:53"
+}
+
+// queryDNS send a recursive query to system name server, it is used for
+// record types that net.Resolver does not support.
+func queryDNS(name string, qtype dnsmessage.Type) (*dnsmessage.Message, error) {
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:               uint16(rand.Intn(65536)),
+			RecursionDesired: true,
+		},
+		Questions: []dnsmessage.Question{{
+			Name:  qname,
+			Type:  qtype,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	query, err := msg.Pack()
+	if err != nil {
+		return nil, err
+	}
+	server := dnsServer()
+	resp, err := exchangeDNS("udp", server, query)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Truncated {
+		resp, err = exchangeDNS("tcp", server, query)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resp.ID != msg.ID {
+		return nil, fmt.Errorf("DNS response id mismatch")
+	}
+	return resp, nil
+}
+
+func exchangeDNS(network, server string, query []byte) (*dnsmessage.Message, error) {
+	conn, err := net.DialTimeout(network, server, dnsTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(dnsTimeout))
+	var buf []byte
+	if network == "tcp" {
+		req := make([]byte, 2+len(query))
+		binary.BigEndian.PutUint16(req, uint16(len(query)))
+		copy(req[2:], query)
+		if _, err = conn.Write(req); err != nil {
+			return nil, err
+		}
+		lbuf := make([]byte, 2)
+		if _, err = io.ReadFull(conn, lbuf); err != nil {
+			return nil, err
+		}
+		buf = make([]byte, binary.BigEndian.Uint16(lbuf))
+		if _, err = io.ReadFull(conn, buf); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err = conn.Write(query); err != nil {
+			return nil, err
+		}
+		buf = make([]byte, 65535)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		buf = buf[:n]
+	}
+	resp := &dnsmessage.Message{}
+	if err = resp.Unpack(buf); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
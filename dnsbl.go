@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+)
+
+var defaultDNSBLIPLists = []string{
+	"zen.spamhaus.org",
+	"bl.spamcop.net",
+	"b.barracudacentral.org",
+}
+
+var defaultDNSBLDomainLists = []string{
+	"dbl.spamhaus.org",
+	"multi.uribl.com",
+}
+
+type DNSBLListing struct {
+	// Mail host of address, empty for domain queries
+	Host     string
+	Address  string
+	List     string
+	Status   string
+	Listed   bool
+	ErrorMsg string
+}
+
+type DNSBLResult struct {
+	Domain   string
+	Status   string
+	ErrorMsg string
+	Listings []DNSBLListing
+}
+
+type DNSBLResults map[string]DNSBLResult
+
+type DNSBLChecker struct {
+	Domains     []string
+	IPLists     []string
+	DomainLists []string
+	// Address family of mail host addresses, both means all addresses
+	IPProtocol string
+}
+
+func NewDNSBLChecker(domains, ipLists, domainLists []string) *DNSBLChecker {
+	if len(ipLists) == 0 {
+		ipLists = defaultDNSBLIPLists
+	}
+	if len(domainLists) == 0 {
+		domainLists = defaultDNSBLDomainLists
+	}
+	return &DNSBLChecker{
+		Domains:     domains,
+		IPLists:     ipLists,
+		DomainLists: domainLists,
+	}
+}
+
+func (bc *DNSBLChecker) Check() DNSBLResults {
+	var (
+		lock sync.Mutex
+		wg   sync.WaitGroup
+		ret  DNSBLResults = make(DNSBLResults)
+	)
+	wg.Add(len(bc.Domains))
+	for _, item := range bc.Domains {
+		go func(domain string) {
+			br := bc.CheckOneDomain(domain)
+			lock.Lock()
+			ret[domain] = br
+			lock.Unlock()
+			wg.Done()
+		}(item)
+	}
+	wg.Wait()
+	return ret
+}
+
+// CheckOneDomain check domain in domain lists and addresses of its mail hosts
+// in IP lists.
+func (bc *DNSBLChecker) CheckOneDomain(domain string) DNSBLResult {
+	ret := DNSBLResult{
+		Domain:   domain,
+		Status:   "Error",
+		Listings: []DNSBLListing{},
+	}
+	queries := []DNSBLListing{}
+	for _, list := range bc.DomainLists {
+		queries = append(queries, DNSBLListing{List: list})
+	}
+	hosts, err := mailHosts(domain)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+	}
+	seen := make(map[string]bool)
+	family := singleFamily(bc.IPProtocol)
+	for _, host := range hosts {
+		addrs, err := lookupHost(host)
+		if err != nil {
+			ret.ErrorMsg = fmt.Sprintf("Resolve mail host %s failed: %v", host, err)
+			continue
+		}
+		for _, addr := range filterFamily(addrs, family) {
+			if seen[addr] {
+				continue
+			}
+			seen[addr] = true
+			for _, list := range bc.IPLists {
+				queries = append(queries, DNSBLListing{Host: host, Address: addr, List: list})
+			}
+		}
+	}
+	if len(seen) > 0 {
+		ret.Status = "OK"
+	} else if ret.ErrorMsg == "" {
+		ret.ErrorMsg = "Mail hosts have no IP addresses"
+	}
+	if ret.ErrorMsg != "" {
+		log.Printf("DNSBLChecker Error: %s: %s", domain, ret.ErrorMsg)
+	}
+	var wg sync.WaitGroup
+	wg.Add(len(queries))
+	for i := range queries {
+		go func(listing *DNSBLListing) {
+			bc.query(domain, listing)
+			wg.Done()
+		}(&queries[i])
+	}
+	wg.Wait()
+	for _, listing := range queries {
+		if listing.Listed {
+			log.Printf("DNSBLChecker Warning: %s %s %s listed in %s", domain, listing.Host, listing.Address, listing.List)
+		}
+	}
+	ret.Listings = queries
+	return ret
+}
+
+// mailHosts returns MX hosts of domain, domain itself is the mail host when it
+// has no MX records, see RFC 5321 section 5.1.
+func mailHosts(domain string) ([]string, error) {
+	mxs, err := lookupMX(domain)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return []string{domain}, nil
+		}
+		return nil, err
+	}
+	if len(mxs) == 0 {
+		return []string{domain}, nil
+	}
+	ret := []string{}
+	for _, mx := range mxs {
+		host := strings.TrimSuffix(mx.Host, ".")
+		// Null MX means domain accepts no mail, see RFC 7505
+		if host != "" {
+			ret = append(ret, host)
+		}
+	}
+	return ret, nil
+}
+
+func (bc *DNSBLChecker) query(domain string, listing *DNSBLListing) {
+	listing.Status = "Error"
+	name := domain
+	if listing.Address != "" {
+		reversed, err := reverseAddress(listing.Address)
+		if err != nil {
+			listing.ErrorMsg = fmt.Sprintf("%v", err)
+			return
+		}
+		name = reversed
+	}
+	addrs, err := lookupHost(fmt.Sprintf("%s.%s", name, listing.List))
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			listing.Status = "OK"
+			return
+		}
+		listing.ErrorMsg = fmt.Sprintf("%v", err)
+		return
+	}
+	for _, addr := range addrs {
+		// Lists answer 127.255.255.x or 127.0.0.1 for refused queries
+		if strings.HasPrefix(addr, "127.255.255.") || addr == "127.0.0.1" {
+			listing.ErrorMsg = fmt.Sprintf("Query refused by %s: %s", listing.List, addr)
+			return
+		}
+	}
+	for _, addr := range addrs {
+		if strings.HasPrefix(addr, "127.") {
+			listing.Status = "OK"
+			listing.Listed = true
+			return
+		}
+	}
+	listing.Status = "OK"
+}
+
+// reverseAddress returns reversed octets of IPv4 or reversed nibbles of IPv6
+// address, as used by DNSBL zones.
+func reverseAddress(addr string) (string, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return "", fmt.Errorf("Invalid IP address: %s", addr)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d", ip4[3], ip4[2], ip4[1], ip4[0]), nil
+	}
+	nibbles := make([]string, 0, 32)
+	for i := len(ip) - 1; i >= 0; i-- {
+		nibbles = append(nibbles, fmt.Sprintf("%x", ip[i]&0x0f), fmt.Sprintf("%x", ip[i]>>4))
+	}
+	return strings.Join(nibbles, "."), nil
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+const grpcTimeout = 10 * time.Second
+
+// Serving status of grpc.health.v1.HealthCheckResponse
+var grpcServingStatus = map[uint64]string{
+	0: "UNKNOWN",
+	1: "SERVING",
+	2: "NOT_SERVING",
+	3: "SERVICE_UNKNOWN",
+}
+
+type GRPCResult struct {
+	Domain        string
+	Host          string
+	Port          int
+	Service       string
+	IPFamily      string
+	Address       string
+	Status        string
+	ErrorMsg      string
+	ServingStatus string
+	Latency       time.Duration
+}
+
+type GRPCParams struct {
+	Domain   string
+	IPFamily string
+	Config   *GRPCConfig
+}
+
+type GRPCResults map[string]GRPCResult
+
+type GRPCChecker struct {
+	Domains []GRPCConfig
+	// Default ip_protocol of checks
+	IPProtocol string
+}
+
+func NewGRPCChecker(domains []GRPCConfig) *GRPCChecker {
+	return &GRPCChecker{
+		Domains: domains,
+	}
+}
+
+func (gc *GRPCChecker) Check() GRPCResults {
+	var (
+		lock sync.Mutex
+		wg   sync.WaitGroup
+		ret  GRPCResults = make(GRPCResults)
+	)
+	targets := []*GRPCParams{}
+	for i := range gc.Domains {
+		cfg := &gc.Domains[i]
+		protocol := cfg.IPProtocol
+		if protocol == "" {
+			protocol = gc.IPProtocol
+		}
+		domains := cfg.Domains
+		if len(domains) == 0 {
+			domains = []string{cfg.Host}
+		}
+		for _, domain := range domains {
+			for _, family := range ipFamilies(protocol) {
+				targets = append(targets, &GRPCParams{
+					Domain:   domain,
+					IPFamily: family,
+					Config:   cfg,
+				})
+			}
+		}
+	}
+	wg.Add(len(targets))
+	for _, item := range targets {
+		go func(params *GRPCParams) {
+			gr := gc.CheckOneDomain(params)
+			lock.Lock()
+			key := fmt.Sprintf("%s:%d/%s @ %s", params.Config.Host, params.Config.Port, params.Config.Service, params.Domain)
+			ret[familyKey(key, params.IPFamily)] = gr
+			lock.Unlock()
+			if gr.ErrorMsg != "" {
+				log.Printf("GRPCChecker Error: %s: %s:%d -> %v", params.Domain, params.Config.Host, params.Config.Port, gr.ErrorMsg)
+			}
+			wg.Done()
+		}(item)
+	}
+	wg.Wait()
+	return ret
+}
+
+func (gc *GRPCChecker) CheckOneDomain(params *GRPCParams) GRPCResult {
+	cfg := params.Config
+	ret := GRPCResult{
+		Domain:   params.Domain,
+		Host:     cfg.Host,
+		Port:     cfg.Port,
+		Service:  cfg.Service,
+		IPFamily: params.IPFamily,
+		Status:   "Error",
+	}
+	addrs, err := lookupHostFamily(params.Domain, params.IPFamily)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	if len(addrs) == 0 {
+		ret.ErrorMsg = "Domain has no IP addresses"
+		return ret
+	}
+	ret.Address = selectAddress(addrs)
+	raddr := net.JoinHostPort(ret.Address, strconv.Itoa(cfg.Port))
+	transport, err := newGRPCTransport(raddr, params)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	defer transport.CloseIdleConnections()
+
+	ctx, cancel := context.WithTimeout(context.Background(), grpcTimeout)
+	defer cancel()
+	start := time.Now()
+	status, err := grpcHealthCheck(ctx, transport, cfg)
+	ret.Latency = time.Since(start)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	ret.Status = "OK"
+	ret.ServingStatus = status
+	log.Println("[INFO] gRPC", cfg.GetAuthority(), "@", params.Domain, "Service", strconv.Quote(cfg.Service), status, "Latency", ret.Latency)
+	return ret
+}
+
+// newGRPCTransport returns HTTP/2 transport that always connect to raddr,
+// prior knowledge HTTP/2 (h2c) is used when TLS is disabled.
+func newGRPCTransport(raddr string, params *GRPCParams) (*http2.Transport, error) {
+	cfg := params.Config
+	dialer, err := getDialer(cfg.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	var tlsConfig *tls.Config
+	if cfg.TLS {
+		if tlsConfig, err = cfg.GetTLSConfig(); err != nil {
+			return nil, err
+		}
+	}
+	return &http2.Transport{
+		AllowHTTP: !cfg.TLS,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, tcpNetwork(params.IPFamily), raddr)
+			if err != nil || tlsConfig == nil {
+				return conn, err
+			}
+			tlsConn := tls.Client(conn, tlsConfig)
+			if err = tlsConn.HandshakeContext(ctx); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			return tlsConn, nil
+		},
+	}, nil
+}
+
+// grpcHealthCheck call grpc.health.v1.Health/Check and returns serving status,
+// deadline of ctx is sent to server as grpc-timeout. The messages are encoded
+// here so grpc-go is not needed at runtime, it is only used by tests as the
+// reference server.
+func grpcHealthCheck(ctx context.Context, transport http.RoundTripper, cfg *GRPCConfig) (string, error) {
+	scheme := "http"
+	if cfg.TLS {
+		scheme = "https"
+	}
+	u := &url.URL{Scheme: scheme, Host: cfg.GetAuthority(), Path: "/grpc.health.v1.Health/Check"}
+	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), bytes.NewReader(encodeHealthCheckRequest(cfg.Service)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	req.Header.Set("Grpc-Accept-Encoding", "identity")
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set("Grpc-Timeout", fmt.Sprintf("%dm", max(time.Until(deadline).Milliseconds(), 1)))
+	}
+	req.Header.Set("User-Agent", "domain-exporter")
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("gRPC response status not equals to 200, %v", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	// Trailers-only responses carry grpc-status in headers
+	code := resp.Trailer.Get("Grpc-Status")
+	msg := resp.Trailer.Get("Grpc-Message")
+	if code == "" {
+		code = resp.Header.Get("Grpc-Status")
+		msg = resp.Header.Get("Grpc-Message")
+	}
+	if code != "0" {
+		return "", fmt.Errorf("gRPC status %s: %s", code, msg)
+	}
+	// Compressed flag is set although only identity is accepted
+	if len(body) > 0 && body[0] != 0 {
+		return "", fmt.Errorf("gRPC response is compressed with %q, only identity is supported", resp.Header.Get("Grpc-Encoding"))
+	}
+	return decodeHealthCheckResponse(body)
+}
+
+// encodeHealthCheckRequest returns length prefixed HealthCheckRequest message.
+func encodeHealthCheckRequest(service string) []byte {
+	msg := []byte{}
+	if service != "" {
+		// Field 1, wire type 2 (length delimited)
+		msg = append(msg, 0x0a)
+		msg = binary.AppendUvarint(msg, uint64(len(service)))
+		msg = append(msg, service...)
+	}
+	ret := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(ret[1:], uint32(len(msg)))
+	return append(ret, msg...)
+}
+
+// decodeHealthCheckResponse parse length prefixed HealthCheckResponse message.
+func decodeHealthCheckResponse(data []byte) (string, error) {
+	if len(data) < 5 {
+		return "", fmt.Errorf("gRPC response message too short")
+	}
+	size := binary.BigEndian.Uint32(data[1:5])
+	if uint32(len(data)-5) < size {
+		return "", fmt.Errorf("gRPC response message truncated")
+	}
+	msg := data[5 : 5+size]
+	status := uint64(0)
+	for len(msg) > 0 {
+		tag, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return "", fmt.Errorf("Invalid HealthCheckResponse")
+		}
+		msg = msg[n:]
+		switch tag & 7 {
+		case 0:
+			v, n := binary.Uvarint(msg)
+			if n <= 0 {
+				return "", fmt.Errorf("Invalid HealthCheckResponse")
+			}
+			msg = msg[n:]
+			if tag>>3 == 1 {
+				status = v
+			}
+		case 2:
+			l, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < l {
+				return "", fmt.Errorf("Invalid HealthCheckResponse")
+			}
+			msg = msg[n+int(l):]
+		default:
+			return "", fmt.Errorf("Invalid HealthCheckResponse")
+		}
+	}
+	if name, ok := grpcServingStatus[status]; ok {
+		return name, nil
+	}
+	return "UNKNOWN", nil
+}
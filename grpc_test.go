@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// startHealthServer start gRPC server with health service on loopback,
+// returns its port.
+func startHealthServer(t *testing.T, healthServer healthpb.HealthServer, opts ...grpc.ServerOption) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(opts...)
+	healthpb.RegisterHealthServer(server, healthServer)
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func newTestHealthServer() *health.Server {
+	hs := health.NewServer()
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("serving", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("not-serving", healthpb.HealthCheckResponse_NOT_SERVING)
+	return hs
+}
+
+func checkGRPC(cfg *GRPCConfig) GRPCResult {
+	return NewGRPCChecker(nil).CheckOneDomain(&GRPCParams{
+		Domain: "127.0.0.1",
+		Config: cfg,
+	})
+}
+
+func TestGRPCHealthCheck(t *testing.T) {
+	port := startHealthServer(t, newTestHealthServer())
+	for _, c := range []struct {
+		service string
+		status  string
+	}{
+		{"", "SERVING"},
+		{"serving", "SERVING"},
+		{"not-serving", "NOT_SERVING"},
+	} {
+		ret := checkGRPC(&GRPCConfig{Host: "127.0.0.1", Port: port, Service: c.service})
+		if ret.Status != "OK" {
+			t.Fatalf("Check service %q failed: %s", c.service, ret.ErrorMsg)
+		}
+		if ret.ServingStatus != c.status {
+			t.Errorf("Expect service %q %s, got %s", c.service, c.status, ret.ServingStatus)
+		}
+	}
+}
+
+func TestGRPCHealthCheckUnknownService(t *testing.T) {
+	port := startHealthServer(t, newTestHealthServer())
+	ret := checkGRPC(&GRPCConfig{Host: "127.0.0.1", Port: port, Service: "unknown"})
+	if ret.Status != "Error" {
+		t.Fatalf("Expect error of unknown service, got %s", ret.ServingStatus)
+	}
+	// NOT_FOUND status code
+	if !strings.Contains(ret.ErrorMsg, "gRPC status 5") {
+		t.Errorf("Expect NOT_FOUND status, got %s", ret.ErrorMsg)
+	}
+}
+
+// blockingHealthServer never responds until the deadline of request.
+type blockingHealthServer struct {
+	healthpb.UnimplementedHealthServer
+	deadline chan bool
+}
+
+func (s *blockingHealthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	_, ok := ctx.Deadline()
+	s.deadline <- ok
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestGRPCHealthCheckDeadline(t *testing.T) {
+	hs := &blockingHealthServer{deadline: make(chan bool, 1)}
+	port := startHealthServer(t, hs)
+	cfg := &GRPCConfig{Host: "127.0.0.1", Port: port}
+	transport, err := newGRPCTransport(net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), &GRPCParams{Domain: "127.0.0.1", Config: cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer transport.CloseIdleConnections()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err = grpcHealthCheck(ctx, transport, cfg); err == nil {
+		t.Fatal("Expect deadline error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Health check not stopped at deadline, took %v", elapsed)
+	}
+	if !<-hs.deadline {
+		t.Error("Expect deadline sent to server by grpc-timeout")
+	}
+}
+
+func TestGRPCHealthCheckTLS(t *testing.T) {
+	cert, caFile := newTestCertificate(t, "grpc.test")
+	port := startHealthServer(t, newTestHealthServer(), grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+	})))
+	ret := checkGRPC(&GRPCConfig{Host: "127.0.0.1", Port: port, TLS: true, CAFile: caFile, ServerName: "grpc.test"})
+	if ret.Status != "OK" || ret.ServingStatus != "SERVING" {
+		t.Fatalf("Expect SERVING over TLS, got %s %s: %s", ret.Status, ret.ServingStatus, ret.ErrorMsg)
+	}
+	ret = checkGRPC(&GRPCConfig{Host: "127.0.0.1", Port: port, TLS: true, CAFile: caFile, ServerName: "other.test"})
+	if ret.Status != "Error" {
+		t.Fatal("Expect certificate error of mismatched server name")
+	}
+}
+
+// newTestCertificate returns self signed certificate of name and path of its
+// PEM file used as CA file.
+func newTestCertificate(t *testing.T, name string) (tls.Certificate, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{name},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err = os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, caFile
+}
+
+func TestGRPCHealthCheckCompressed(t *testing.T) {
+	port := startHealthServer(t, newTestHealthServer(), grpc.RPCCompressor(grpc.NewGZIPCompressor()))
+	ret := checkGRPC(&GRPCConfig{Host: "127.0.0.1", Port: port})
+	if ret.Status != "Error" {
+		t.Fatal("Expect error of compressed response")
+	}
+	if !strings.Contains(ret.ErrorMsg, "gzip") {
+		t.Errorf("Expect compression in error, got %s", ret.ErrorMsg)
+	}
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SPF allows at most 10 DNS querying terms, see RFC 7208 section 4.6.4
+const spfMaxLookups = 10
+
+type MailRecordResult struct {
+	Status   string
+	Mode     string
+	ErrorMsg string
+}
+
+type MailPolicyResult struct {
+	Domain     string
+	SPF        MailRecordResult
+	SPFLookups int
+	DMARC      MailRecordResult
+	DKIM       map[string]MailRecordResult
+	MTASTS     MailRecordResult
+	TLSRPT     MailRecordResult
+}
+
+type MailPolicyResults map[string]MailPolicyResult
+
+type MailPolicyChecker struct {
+	Domains []MailPolicyConfig
+	// Address family to fetch MTA-STS policy, both means system default
+	IPProtocol string
+}
+
+func NewMailPolicyChecker(domains []MailPolicyConfig) *MailPolicyChecker {
+	return &MailPolicyChecker{
+		Domains: domains,
+	}
+}
+
+func newMailRecordResult() MailRecordResult {
+	return MailRecordResult{
+		Status: "Error",
+		Mode:   "none",
+	}
+}
+
+func (mc *MailPolicyChecker) Check() MailPolicyResults {
+	var (
+		lock sync.Mutex
+		wg   sync.WaitGroup
+		ret  MailPolicyResults = make(MailPolicyResults)
+	)
+	wg.Add(len(mc.Domains))
+	for _, item := range mc.Domains {
+		go func(cfg MailPolicyConfig) {
+			mr := mc.CheckOneDomain(cfg)
+			lock.Lock()
+			ret[cfg.Domain] = mr
+			lock.Unlock()
+			wg.Done()
+		}(item)
+	}
+	wg.Wait()
+	return ret
+}
+
+func (mc *MailPolicyChecker) CheckOneDomain(cfg MailPolicyConfig) MailPolicyResult {
+	ret := MailPolicyResult{
+		Domain: cfg.Domain,
+		DKIM:   make(map[string]MailRecordResult),
+	}
+	ret.SPF, ret.SPFLookups = mc.checkSPF(cfg.Domain)
+	ret.DMARC = mc.checkDMARC(cfg.Domain)
+	for _, selector := range cfg.DKIMSelectors {
+		ret.DKIM[selector] = mc.checkDKIM(cfg.Domain, selector)
+	}
+	ret.MTASTS = mc.checkMTASTS(cfg.Domain)
+	ret.TLSRPT = mc.checkTLSRPT(cfg.Domain)
+	log.Println("[INFO] Mail Policy", cfg.Domain, "SPF:", ret.SPF.Status, "DMARC:", ret.DMARC.Status, "MTA-STS:", ret.MTASTS.Status, "TLS-RPT:", ret.TLSRPT.Status)
+	return ret
+}
+
+// findRecord returns the only TXT record of name starting with prefix.
+// hasVersionTag returns true if record starts with version tag, the tag must
+// be followed by a separator so "v=spf10" is not a "v=spf1" record.
+func hasVersionTag(record, tag string) bool {
+	if len(record) < len(tag) || !strings.EqualFold(record[:len(tag)], tag) {
+		return false
+	}
+	rest := record[len(tag):]
+	return rest == "" || rest[0] == ' ' || rest[0] == '\t' || rest[0] == ';'
+}
+
+func findRecord(name, prefix string) (string, error) {
+	txts, err := lookupTXT(name)
+	if err != nil {
+		return "", err
+	}
+	found := []string{}
+	for _, txt := range txts {
+		if hasVersionTag(txt, prefix) {
+			found = append(found, txt)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("No %s record found for %s", prefix, name)
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("Multiple %s records found for %s", prefix, name)
+	}
+}
+
+// parseTags parse records like "v=DMARC1; p=reject" into a map with lower case keys.
+func parseTags(record string) map[string]string {
+	ret := make(map[string]string)
+	for _, part := range strings.Split(record, ";") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		ret[strings.ToLower(strings.TrimSpace(kv[0]))] = strings.TrimSpace(kv[1])
+	}
+	return ret
+}
+
+func (mc *MailPolicyChecker) checkSPF(domain string) (MailRecordResult, int) {
+	ret := newMailRecordResult()
+	lookups := 0
+	mode, err := mc.resolveSPF(domain, &lookups, 0)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret, lookups
+	}
+	if lookups > spfMaxLookups {
+		ret.ErrorMsg = fmt.Sprintf("SPF requires %d DNS lookups, limit is %d", lookups, spfMaxLookups)
+		return ret, lookups
+	}
+	ret.Status = "OK"
+	ret.Mode = mode
+	return ret, lookups
+}
+
+// resolveSPF walk through SPF record of domain, count DNS lookups and return
+// the qualifier of top level "all" mechanism as policy mode.
+func (mc *MailPolicyChecker) resolveSPF(domain string, lookups *int, depth int) (string, error) {
+	if depth > spfMaxLookups {
+		return "", fmt.Errorf("SPF include loop detected at %s", domain)
+	}
+	record, err := findRecord(domain, "v=spf1")
+	if err != nil {
+		return "", err
+	}
+	mode := "neutral"
+	redirect := ""
+	allSeen := false
+	for _, term := range strings.Fields(record)[1:] {
+		term = strings.ToLower(term)
+		qualifier := "+"
+		if strings.ContainsAny(term[:1], "+-~?") {
+			qualifier = term[:1]
+			term = term[1:]
+		}
+		switch {
+		case strings.HasPrefix(term, "include:"):
+			*lookups++
+			if _, err := mc.resolveSPF(term[len("include:"):], lookups, depth+1); err != nil {
+				return "", err
+			}
+		case strings.HasPrefix(term, "redirect="):
+			redirect = term[len("redirect="):]
+		case term == "a" || strings.HasPrefix(term, "a:") || strings.HasPrefix(term, "a/"),
+			term == "mx" || strings.HasPrefix(term, "mx:") || strings.HasPrefix(term, "mx/"),
+			term == "ptr" || strings.HasPrefix(term, "ptr:"),
+			strings.HasPrefix(term, "exists:"):
+			*lookups++
+		case term == "all":
+			mode = decodeSPFQualifier(qualifier)
+			allSeen = true
+		}
+	}
+	// redirect is ignored when there is an "all" mechanism, see RFC 7208 section 6.1
+	if redirect != "" && !allSeen {
+		*lookups++
+		return mc.resolveSPF(redirect, lookups, depth+1)
+	}
+	return mode, nil
+}
+
+func decodeSPFQualifier(qualifier string) string {
+	switch qualifier {
+	case "-":
+		return "fail"
+	case "~":
+		return "softfail"
+	case "?":
+		return "neutral"
+	default:
+		return "pass"
+	}
+}
+
+func (mc *MailPolicyChecker) checkDMARC(domain string) MailRecordResult {
+	ret := newMailRecordResult()
+	record, err := findRecord("_dmarc."+domain, "v=DMARC1")
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	policy := strings.ToLower(parseTags(record)["p"])
+	switch policy {
+	case "none", "quarantine", "reject":
+		ret.Status = "OK"
+		ret.Mode = policy
+	default:
+		ret.ErrorMsg = fmt.Sprintf("Invalid DMARC policy: %q", policy)
+	}
+	return ret
+}
+
+func (mc *MailPolicyChecker) checkDKIM(domain, selector string) MailRecordResult {
+	ret := newMailRecordResult()
+	txts, err := lookupTXT(fmt.Sprintf("%s._domainkey.%s", selector, domain))
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	for _, txt := range txts {
+		tags := parseTags(txt)
+		if v, ok := tags["v"]; ok && v != "DKIM1" {
+			continue
+		}
+		if tags["p"] == "" {
+			// Empty public key means the key is revoked
+			ret.ErrorMsg = "DKIM key revoked"
+			continue
+		}
+		ret.Status = "OK"
+		ret.ErrorMsg = ""
+		ret.Mode = strings.ToLower(tags["k"])
+		if ret.Mode == "" {
+			ret.Mode = "rsa"
+		}
+		return ret
+	}
+	if ret.ErrorMsg == "" {
+		ret.ErrorMsg = "No DKIM record found"
+	}
+	return ret
+}
+
+func (mc *MailPolicyChecker) checkMTASTS(domain string) MailRecordResult {
+	ret := newMailRecordResult()
+	record, err := findRecord("_mta-sts."+domain, "v=STSv1")
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	if parseTags(record)["id"] == "" {
+		ret.ErrorMsg = "MTA-STS record has no id"
+		return ret
+	}
+	policy, err := mc.fetchMTASTSPolicy(domain)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	if policy["version"] != "STSv1" {
+		ret.ErrorMsg = fmt.Sprintf("Invalid MTA-STS policy version: %q", policy["version"])
+		return ret
+	}
+	switch policy["mode"] {
+	case "enforce", "testing", "none":
+		ret.Mode = policy["mode"]
+	default:
+		ret.ErrorMsg = fmt.Sprintf("Invalid MTA-STS policy mode: %q", policy["mode"])
+		return ret
+	}
+	if policy["max_age"] == "" {
+		ret.ErrorMsg = "MTA-STS policy has no max_age"
+		return ret
+	}
+	ret.Status = "OK"
+	return ret
+}
+
+func (mc *MailPolicyChecker) fetchMTASTSPolicy(domain string) (map[string]string, error) {
+	dialer := getGlobalDialer()
+	network := tcpNetwork(singleFamily(mc.IPProtocol))
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+		Timeout: 10 * time.Second,
+		// Policy fetch must not follow redirects, see RFC 8461 section 3.3
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	defer client.CloseIdleConnections()
+	resp, err := client.Get(fmt.Sprintf("https://mta-sts.%s/.well-known/mta-sts.txt", domain))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("MTA-STS policy status not equals to 200, %v", resp.StatusCode)
+	}
+	// Media type must be text/plain, see RFC 8461 section 3.2
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err != nil || mediaType != "text/plain" {
+		return nil, fmt.Errorf("MTA-STS policy content type is not text/plain, %q", resp.Header.Get("Content-Type"))
+	}
+	ret := make(map[string]string)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(kv[0])
+		// Policy may have multiple mx lines, only record existence
+		ret[key] = strings.TrimSpace(kv[1])
+	}
+	return ret, scanner.Err()
+}
+
+func (mc *MailPolicyChecker) checkTLSRPT(domain string) MailRecordResult {
+	ret := newMailRecordResult()
+	record, err := findRecord("_smtp._tls."+domain, "v=TLSRPTv1")
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	if parseTags(record)["rua"] == "" {
+		ret.ErrorMsg = "TLS-RPT record has no rua"
+		return ret
+	}
+	ret.Status = "OK"
+	ret.Mode = "report"
+	return ret
+}
@@ -12,7 +12,7 @@ var (
 			Name: "domain_certificate_status",
 			Help: "Domain certificate status, 0 means error, 1 means OK.",
 		},
-		[]string{"domain", "cname"},
+		[]string{"domain", "cname", "ip_version"},
 	)
 
 	DomainCertificateExpireDays = prometheus.NewGaugeVec(
@@ -20,9 +20,25 @@ var (
 			Name: "domain_certificate_expire_days",
 			Help: "Domain certificate expire days.",
 		},
+		[]string{"domain", "cname", "ip_version"},
+	)
+
+	DomainCertificateCAAStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_certificate_caa_status",
+			Help: "Domain certificate CAA lookup status, 0 means error, 1 means OK.",
+		},
 		[]string{"domain", "cname"},
 	)
 
+	DomainCertificateCAACompliant = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_certificate_caa_compliant",
+			Help: "Domain certificate issuer allowed by CAA records, 0 means not allowed, 1 means allowed.",
+		},
+		[]string{"domain", "cname", "issuer"},
+	)
+
 	DomainWhoisStatus = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "domain_whois_status",
@@ -39,12 +55,68 @@ var (
 		[]string{"domain"},
 	)
 
+	DomainWhoisEPPStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_whois_epp_status",
+			Help: "Domain whois EPP status code, 0 means absent, 1 means present.",
+		},
+		[]string{"domain", "status"},
+	)
+
+	DomainWhoisTransferLocked = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_whois_transfer_locked",
+			Help: "Domain transfer prohibited by registrar or registry, 0 means unlocked, 1 means locked.",
+		},
+		[]string{"domain"},
+	)
+
+	DomainWhoisInGraceOrRedemption = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_whois_in_grace_or_redemption",
+			Help: "Domain is in auto renew grace, redemption or pending delete period, 0 means no, 1 means yes.",
+		},
+		[]string{"domain"},
+	)
+
+	DomainWhoisInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_whois_info",
+			Help: "Domain whois registrar, name servers and DNSSEC status, value is always 1.",
+		},
+		[]string{"domain", "registrar", "registrar_iana_id", "nameservers", "dnssec"},
+	)
+
+	DomainWhoisCreated = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_whois_created_timestamp_seconds",
+			Help: "Domain whois creation date in unix timestamp.",
+		},
+		[]string{"domain"},
+	)
+
+	DomainWhoisUpdated = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_whois_updated_timestamp_seconds",
+			Help: "Domain whois last updated date in unix timestamp.",
+		},
+		[]string{"domain"},
+	)
+
+	DomainWhoisChanges = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "domain_whois_changes_total",
+			Help: "Domain whois registrar or name servers changes, field is registrar or nameservers.",
+		},
+		[]string{"domain", "field"},
+	)
+
 	DomainResolveStatus = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "domain_resolve_status",
 			Help: "Domain resolve status, 0 means error, 1 means OK.",
 		},
-		[]string{"domain"},
+		[]string{"domain", "ip_version"},
 	)
 
 	DomainResolveIPs = prometheus.NewGaugeVec(
@@ -52,7 +124,31 @@ var (
 			Name: "domain_resolve_ips",
 			Help: "Domain resolved IP addresses",
 		},
-		[]string{"domain"},
+		[]string{"domain", "ip_version"},
+	)
+
+	DomainResolvePingReachable = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_resolve_ping_reachable",
+			Help: "Domain resolved address replies to ping, 0 means unreachable, 1 means reachable.",
+		},
+		[]string{"domain", "address", "ip_version"},
+	)
+
+	DomainResolvePingLoss = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_resolve_ping_packet_loss_ratio",
+			Help: "Domain resolved address ping packet loss ratio, from 0 to 1.",
+		},
+		[]string{"domain", "address", "ip_version"},
+	)
+
+	DomainResolvePingRTT = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_resolve_ping_rtt_seconds",
+			Help: "Domain resolved address ping round trip time in seconds.",
+		},
+		[]string{"domain", "address", "ip_version", "stat"},
 	)
 
 	DomainRequestStatus = prometheus.NewGaugeVec(
@@ -60,36 +156,525 @@ var (
 			Name: "domain_request_status",
 			Help: "Domain request status, 0 means error, 1 means OK.",
 		},
-		[]string{"domain", "host", "path"},
+		[]string{"domain", "host", "path", "ip_version"},
+	)
+
+	// Request attempts, failures and last error are not reset on reload.
+	DomainRequestAttempts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "domain_request_attempts_total",
+			Help: "Domain request check attempts.",
+		},
+		[]string{"domain", "host", "path", "ip_version"},
+	)
+
+	DomainRequestFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "domain_request_failures_total",
+			Help: "Domain request check failures by reason.",
+		},
+		[]string{"domain", "host", "path", "reason", "ip_version"},
+	)
+
+	DomainRequestLastError = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_request_last_error_info",
+			Help: "Domain request last error, value is the unix timestamp of the error.",
+		},
+		[]string{"domain", "host", "path", "reason", "status", "ip_version"},
+	)
+
+	DomainRequestContentHash = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_request_content_hash_info",
+			Help: "Domain request SHA-256 of normalized response body, value is always 1.",
+		},
+		[]string{"domain", "host", "path", "hash", "ip_version"},
 	)
 
-	DomainRequestError = prometheus.NewCounterVec(
+	DomainRequestContentChanges = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "domain_request_error",
-			Help: "Domain request error Log.",
+			Name: "domain_request_content_changes_total",
+			Help: "Domain request response body content changes.",
+		},
+		[]string{"domain", "host", "path", "ip_version"},
+	)
+
+	DomainRequestContentLastChanged = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_request_content_last_changed_timestamp_seconds",
+			Help: "Domain request unix timestamp of last response body content change.",
+		},
+		[]string{"domain", "host", "path", "ip_version"},
+	)
+
+	DomainRequestRedirects = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_request_redirects",
+			Help: "Domain request redirects count.",
+		},
+		[]string{"domain", "host", "path", "ip_version"},
+	)
+
+	DomainRequestRedirectHop = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_request_redirect_hop_info",
+			Help: "Domain request redirect hop status and location, value is always 1.",
+		},
+		[]string{"domain", "host", "path", "hop", "url", "status", "location", "ip_version"},
+	)
+
+	DomainRequestHttpsRedirect = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_request_https_redirect",
+			Help: "Domain request HTTP redirects to HTTPS, 0 means not redirected, 1 means redirected.",
+		},
+		[]string{"domain", "host", "path", "ip_version"},
+	)
+
+	DomainRequestHSTSMaxAge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_request_hsts_max_age_seconds",
+			Help: "Domain request HSTS max-age in seconds, 0 means no HSTS header.",
+		},
+		[]string{"domain", "host", "path", "ip_version"},
+	)
+
+	DomainRequestHSTSIncludeSubDomains = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_request_hsts_include_subdomains",
+			Help: "Domain request HSTS includeSubDomains directive, 0 means absent, 1 means present.",
+		},
+		[]string{"domain", "host", "path", "ip_version"},
+	)
+
+	DomainRequestHSTSPreload = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_request_hsts_preload",
+			Help: "Domain request HSTS preload directive, 0 means absent, 1 means present.",
+		},
+		[]string{"domain", "host", "path", "ip_version"},
+	)
+
+	DomainRequestSecurityHeader = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_request_security_header_compliant",
+			Help: "Domain request security header compliance, 0 means not compliant, 1 means compliant.",
+		},
+		[]string{"domain", "host", "path", "header", "ip_version"},
+	)
+
+	DomainRequestSecurityScore = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_request_security_header_score",
+			Help: "Domain request ratio of compliant security headers, from 0 to 1.",
+		},
+		[]string{"domain", "host", "path", "ip_version"},
+	)
+
+	DomainRequestAssets = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_request_assets",
+			Help: "Domain request page assets checked.",
+		},
+		[]string{"domain", "host", "path", "ip_version"},
+	)
+
+	DomainRequestBrokenAssets = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_request_broken_assets",
+			Help: "Domain request page assets that cannot be fetched.",
+		},
+		[]string{"domain", "host", "path", "ip_version"},
+	)
+
+	DomainRequestBrokenAsset = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_request_broken_asset_info",
+			Help: "Domain request page asset that cannot be fetched, value is always 1.",
+		},
+		[]string{"domain", "host", "path", "asset", "status", "ip_version"},
+	)
+
+	DomainRequestCertificateExpireDays = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_request_certificate_expire_days",
+			Help: "Domain request served certificate expire days.",
+		},
+		[]string{"domain", "host", "path", "ip_version"},
+	)
+
+	DomainRequestProtocol = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_request_protocol_info",
+			Help: "Domain request negotiated protocol, value is always 1.",
+		},
+		[]string{"domain", "host", "path", "protocol", "ip_version"},
+	)
+
+	DomainRequestDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_request_duration_seconds",
+			Help: "Domain request duration of each phase in seconds, phase is dns, connect, tls, ttfb, transfer, total or assets.",
+		},
+		[]string{"domain", "host", "path", "phase", "ip_version"},
+	)
+
+	DomainRequestAssertionFailed = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_request_assertion_failed",
+			Help: "Domain request response assertion failed, value is always 1.",
+		},
+		[]string{"domain", "host", "path", "reason", "ip_version"},
+	)
+
+	DomainMailPolicyStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_mail_policy_status",
+			Help: "Domain mail policy record status, 0 means error, 1 means OK.",
+		},
+		[]string{"domain", "record"},
+	)
+
+	DomainMailPolicyMode = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_mail_policy_mode",
+			Help: "Domain mail policy record mode, value is always 1.",
+		},
+		[]string{"domain", "record", "mode"},
+	)
+
+	DomainSPFLookups = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_spf_dns_lookups",
+			Help: "Domain SPF record DNS lookups count, should not exceed 10.",
+		},
+		[]string{"domain"},
+	)
+
+	DomainDKIMStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_dkim_status",
+			Help: "Domain DKIM selector status, 0 means error, 1 means OK.",
+		},
+		[]string{"domain", "selector"},
+	)
+
+	DomainMXStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_mx_status",
+			Help: "Domain MX host SMTP status, 0 means error, 1 means OK.",
+		},
+		[]string{"domain", "mx", "ip_version"},
+	)
+
+	DomainMXLatency = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_mx_latency_seconds",
+			Help: "Domain MX host SMTP connect and banner latency in seconds.",
+		},
+		[]string{"domain", "mx", "ip_version"},
+	)
+
+	DomainMXStartTLS = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_mx_starttls",
+			Help: "Domain MX host STARTTLS support, 0 means not offered, 1 means offered.",
+		},
+		[]string{"domain", "mx", "ip_version"},
+	)
+
+	DomainMXCertificateStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_mx_certificate_status",
+			Help: "Domain MX host STARTTLS certificate status, 0 means error, 1 means OK.",
+		},
+		[]string{"domain", "mx", "ip_version"},
+	)
+
+	DomainMXCertificateExpireDays = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_mx_certificate_expire_days",
+			Help: "Domain MX host STARTTLS certificate expire days.",
+		},
+		[]string{"domain", "mx", "ip_version"},
+	)
+
+	DomainTakeoverStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_takeover_status",
+			Help: "Domain takeover check status, 0 means error, 1 means OK.",
+		},
+		[]string{"domain"},
+	)
+
+	DomainTakeoverRisk = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_takeover_risk",
+			Help: "Domain CNAME takeover risk, 0 means no risk, 1 means dangling CNAME or unclaimed resource.",
+		},
+		[]string{"domain", "cname", "service", "reason"},
+	)
+
+	DomainTyposquatPermutations = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_typosquat_permutations",
+			Help: "Domain lookalike permutations generated.",
+		},
+		[]string{"domain"},
+	)
+
+	DomainTyposquatRegistered = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_typosquat_registered",
+			Help: "Domain lookalike permutations that are registered.",
+		},
+		[]string{"domain"},
+	)
+
+	DomainTyposquatHit = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_typosquat_hit_info",
+			Help: "Domain registered lookalike permutation, value is always 1.",
+		},
+		[]string{"domain", "permutation", "kind"},
+	)
+
+	DomainDNSBLStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_dnsbl_status",
+			Help: "Domain DNS blocklist query status, 0 means error, 1 means OK.",
+		},
+		[]string{"domain", "host", "address", "list"},
+	)
+
+	DomainDNSBLListed = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_dnsbl_listed",
+			Help: "Domain or address of its mail host listed in DNS blocklist, 0 means not listed, 1 means listed.",
+		},
+		[]string{"domain", "host", "address", "list"},
+	)
+
+	DomainTCPStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_tcp_status",
+			Help: "Domain TCP check status, 0 means error, 1 means OK.",
+		},
+		[]string{"domain", "host", "port", "ip_version"},
+	)
+
+	DomainTCPConnectLatency = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_tcp_connect_latency_seconds",
+			Help: "Domain TCP connect latency in seconds.",
+		},
+		[]string{"domain", "host", "port", "ip_version"},
+	)
+
+	DomainTCPCertificateExpireDays = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_tcp_certificate_expire_days",
+			Help: "Domain TCP check TLS certificate expire days.",
+		},
+		[]string{"domain", "host", "port", "ip_version"},
+	)
+
+	DomainGRPCStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_grpc_status",
+			Help: "Domain gRPC health check status, 0 means error, 1 means OK.",
+		},
+		[]string{"domain", "host", "port", "service", "ip_version"},
+	)
+
+	DomainGRPCServing = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_grpc_serving",
+			Help: "Domain gRPC service serving, 0 means not serving, 1 means serving.",
+		},
+		[]string{"domain", "host", "port", "service", "ip_version"},
+	)
+
+	DomainGRPCServingStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_grpc_serving_status_info",
+			Help: "Domain gRPC health check serving status, value is always 1.",
+		},
+		[]string{"domain", "host", "port", "service", "ip_version", "serving_status"},
+	)
+
+	DomainGRPCLatency = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_grpc_latency_seconds",
+			Help: "Domain gRPC health check latency in seconds.",
+		},
+		[]string{"domain", "host", "port", "service", "ip_version"},
+	)
+
+	DomainWebSocketStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_websocket_status",
+			Help: "Domain WebSocket check status, 0 means error, 1 means OK.",
+		},
+		[]string{"domain", "host", "path", "ip_version"},
+	)
+
+	DomainWebSocketStatusCode = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_websocket_status_code",
+			Help: "Domain WebSocket upgrade response status code, 101 means upgraded, 0 means no response.",
+		},
+		[]string{"domain", "host", "path", "ip_version"},
+	)
+
+	DomainWebSocketHandshake = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_websocket_handshake_seconds",
+			Help: "Domain WebSocket connect and upgrade handshake time in seconds.",
+		},
+		[]string{"domain", "host", "path", "ip_version"},
+	)
+
+	DomainWebSocketRTT = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "domain_websocket_rtt_seconds",
+			Help: "Domain WebSocket message round trip time in seconds.",
 		},
-		[]string{"domain", "host", "path", "address", "status"},
+		[]string{"domain", "host", "path", "ip_version"},
 	)
 )
 
 func init() {
 	registry.MustRegister(DomainCertificateStatus)
 	registry.MustRegister(DomainCertificateExpireDays)
+	registry.MustRegister(DomainCertificateCAAStatus)
+	registry.MustRegister(DomainCertificateCAACompliant)
 	registry.MustRegister(DomainWhoisStatus)
 	registry.MustRegister(DomainWhoisExpireDays)
+	registry.MustRegister(DomainWhoisEPPStatus)
+	registry.MustRegister(DomainWhoisTransferLocked)
+	registry.MustRegister(DomainWhoisInGraceOrRedemption)
+	registry.MustRegister(DomainWhoisInfo)
+	registry.MustRegister(DomainWhoisCreated)
+	registry.MustRegister(DomainWhoisUpdated)
+	registry.MustRegister(DomainWhoisChanges)
 	registry.MustRegister(DomainResolveStatus)
 	registry.MustRegister(DomainResolveIPs)
+	registry.MustRegister(DomainResolvePingReachable)
+	registry.MustRegister(DomainResolvePingLoss)
+	registry.MustRegister(DomainResolvePingRTT)
 	registry.MustRegister(DomainRequestStatus)
-	registry.MustRegister(DomainRequestError)
+	registry.MustRegister(DomainRequestAttempts)
+	registry.MustRegister(DomainRequestFailures)
+	registry.MustRegister(DomainRequestLastError)
+	registry.MustRegister(DomainRequestAssertionFailed)
+	registry.MustRegister(DomainRequestDuration)
+	registry.MustRegister(DomainRequestProtocol)
+	registry.MustRegister(DomainRequestCertificateExpireDays)
+	registry.MustRegister(DomainRequestContentHash)
+	registry.MustRegister(DomainRequestContentChanges)
+	registry.MustRegister(DomainRequestContentLastChanged)
+	registry.MustRegister(DomainRequestRedirects)
+	registry.MustRegister(DomainRequestRedirectHop)
+	registry.MustRegister(DomainRequestHttpsRedirect)
+	registry.MustRegister(DomainRequestHSTSMaxAge)
+	registry.MustRegister(DomainRequestHSTSIncludeSubDomains)
+	registry.MustRegister(DomainRequestHSTSPreload)
+	registry.MustRegister(DomainRequestSecurityHeader)
+	registry.MustRegister(DomainRequestSecurityScore)
+	registry.MustRegister(DomainRequestAssets)
+	registry.MustRegister(DomainRequestBrokenAssets)
+	registry.MustRegister(DomainRequestBrokenAsset)
+	registry.MustRegister(DomainMailPolicyStatus)
+	registry.MustRegister(DomainMailPolicyMode)
+	registry.MustRegister(DomainSPFLookups)
+	registry.MustRegister(DomainDKIMStatus)
+	registry.MustRegister(DomainMXStatus)
+	registry.MustRegister(DomainMXLatency)
+	registry.MustRegister(DomainMXStartTLS)
+	registry.MustRegister(DomainMXCertificateStatus)
+	registry.MustRegister(DomainMXCertificateExpireDays)
+	registry.MustRegister(DomainTakeoverStatus)
+	registry.MustRegister(DomainTakeoverRisk)
+	registry.MustRegister(DomainTyposquatPermutations)
+	registry.MustRegister(DomainTyposquatRegistered)
+	registry.MustRegister(DomainTyposquatHit)
+	registry.MustRegister(DomainDNSBLStatus)
+	registry.MustRegister(DomainDNSBLListed)
+	registry.MustRegister(DomainTCPStatus)
+	registry.MustRegister(DomainTCPConnectLatency)
+	registry.MustRegister(DomainTCPCertificateExpireDays)
+	registry.MustRegister(DomainGRPCStatus)
+	registry.MustRegister(DomainGRPCServing)
+	registry.MustRegister(DomainGRPCServingStatus)
+	registry.MustRegister(DomainGRPCLatency)
+	registry.MustRegister(DomainWebSocketStatus)
+	registry.MustRegister(DomainWebSocketStatusCode)
+	registry.MustRegister(DomainWebSocketHandshake)
+	registry.MustRegister(DomainWebSocketRTT)
 }
 
 func ResetAllMetrics() {
 	DomainCertificateStatus.Reset()
 	DomainCertificateExpireDays.Reset()
+	DomainCertificateCAAStatus.Reset()
+	DomainCertificateCAACompliant.Reset()
 	DomainWhoisStatus.Reset()
 	DomainWhoisExpireDays.Reset()
+	DomainWhoisEPPStatus.Reset()
+	DomainWhoisTransferLocked.Reset()
+	DomainWhoisInGraceOrRedemption.Reset()
+	DomainWhoisInfo.Reset()
+	DomainWhoisCreated.Reset()
+	DomainWhoisUpdated.Reset()
 	DomainResolveStatus.Reset()
 	DomainResolveIPs.Reset()
+	DomainResolvePingReachable.Reset()
+	DomainResolvePingLoss.Reset()
+	DomainResolvePingRTT.Reset()
 	DomainRequestStatus.Reset()
-	DomainRequestError.Reset()
+	DomainRequestAssertionFailed.Reset()
+	DomainRequestDuration.Reset()
+	DomainRequestProtocol.Reset()
+	DomainRequestCertificateExpireDays.Reset()
+	DomainRequestContentHash.Reset()
+	DomainRequestRedirects.Reset()
+	DomainRequestRedirectHop.Reset()
+	DomainRequestHttpsRedirect.Reset()
+	DomainRequestHSTSMaxAge.Reset()
+	DomainRequestHSTSIncludeSubDomains.Reset()
+	DomainRequestHSTSPreload.Reset()
+	DomainRequestSecurityHeader.Reset()
+	DomainRequestSecurityScore.Reset()
+	DomainRequestAssets.Reset()
+	DomainRequestBrokenAssets.Reset()
+	DomainRequestBrokenAsset.Reset()
+	DomainMailPolicyStatus.Reset()
+	DomainMailPolicyMode.Reset()
+	DomainSPFLookups.Reset()
+	DomainDKIMStatus.Reset()
+	DomainMXStatus.Reset()
+	DomainMXLatency.Reset()
+	DomainMXStartTLS.Reset()
+	DomainMXCertificateStatus.Reset()
+	DomainMXCertificateExpireDays.Reset()
+	DomainTakeoverStatus.Reset()
+	DomainTakeoverRisk.Reset()
+	DomainTyposquatPermutations.Reset()
+	DomainTyposquatRegistered.Reset()
+	DomainTyposquatHit.Reset()
+	DomainDNSBLStatus.Reset()
+	DomainDNSBLListed.Reset()
+	DomainTCPStatus.Reset()
+	DomainTCPConnectLatency.Reset()
+	DomainTCPCertificateExpireDays.Reset()
+	DomainGRPCStatus.Reset()
+	DomainGRPCServing.Reset()
+	DomainGRPCServingStatus.Reset()
+	DomainGRPCLatency.Reset()
+	DomainWebSocketStatus.Reset()
+	DomainWebSocketStatusCode.Reset()
+	DomainWebSocketHandshake.Reset()
+	DomainWebSocketRTT.Reset()
 }
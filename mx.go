@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+type MXResult struct {
+	Domain         string
+	MX             string
+	IPFamily       string
+	Status         string
+	ErrorMsg       string
+	Latency        time.Duration
+	StartTLS       bool
+	CertStatus     string
+	CertExpireAt   time.Time
+	CertExpireDays int
+}
+
+type MXResults map[string]MXResult
+
+type MXChecker struct {
+	Domains    []string
+	IPProtocol string
+}
+
+func NewMXChecker(domains []string) *MXChecker {
+	return &MXChecker{
+		Domains: domains,
+	}
+}
+
+func (mc *MXChecker) Check() MXResults {
+	var (
+		lock sync.Mutex
+		wg   sync.WaitGroup
+		ret  MXResults = make(MXResults)
+	)
+	wg.Add(len(mc.Domains))
+	for _, item := range mc.Domains {
+		go func(domain string) {
+			results := mc.CheckOneDomain(domain)
+			lock.Lock()
+			for _, mr := range results {
+				key := fmt.Sprintf("%s @ %s", mr.MX, mr.Domain)
+				ret[familyKey(key, mr.IPFamily)] = mr
+			}
+			lock.Unlock()
+			wg.Done()
+		}(item)
+	}
+	wg.Wait()
+	return ret
+}
+
+func (mc *MXChecker) CheckOneDomain(domain string) []MXResult {
+	mxs, err := lookupMX(domain)
+	if err == nil && len(mxs) == 0 {
+		err = fmt.Errorf("Domain has no MX records")
+	}
+	if err != nil {
+		log.Printf("MXChecker Error: %s: %v", domain, err)
+		return []MXResult{{
+			Domain:     domain,
+			Status:     "Error",
+			CertStatus: "Error",
+			ErrorMsg:   fmt.Sprintf("%v", err),
+		}}
+	}
+	families := ipFamilies(mc.IPProtocol)
+	ret := make([]MXResult, len(mxs)*len(families))
+	var wg sync.WaitGroup
+	wg.Add(len(ret))
+	for i, mx := range mxs {
+		for j, family := range families {
+			go func(idx int, host, family string) {
+				ret[idx] = mc.CheckOneMX(domain, host, family)
+				wg.Done()
+			}(i*len(families)+j, strings.TrimSuffix(mx.Host, "."), family)
+		}
+	}
+	wg.Wait()
+	return ret
+}
+
+func (mc *MXChecker) CheckOneMX(domain, host, family string) MXResult {
+	ret := MXResult{
+		Domain:     domain,
+		MX:         host,
+		IPFamily:   family,
+		Status:     "Error",
+		CertStatus: "Error",
+	}
+	start := time.Now()
+	conn, err := dialTimeoutWith(getGlobalDialer(), tcpNetwork(family), net.JoinHostPort(host, "25"), 10*time.Second)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	// NewClient reads the SMTP banner
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	defer client.Close()
+	ret.Latency = time.Since(start)
+	if err = client.Hello("localhost"); err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	ret.Status = "OK"
+	ret.StartTLS, _ = client.Extension("STARTTLS")
+	if !ret.StartTLS {
+		ret.ErrorMsg = "STARTTLS not offered"
+		return ret
+	}
+	if err = client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	connStat, _ := client.TLSConnectionState()
+	et, err := getCertExpireTime(connStat)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	ret.CertStatus = "OK"
+	ret.CertExpireAt = et
+	ret.CertExpireDays = expireDays(et)
+	client.Quit()
+	log.Println("[INFO] MX", host, "of", domain, "Latency", ret.Latency, "Certificate Expire After", ret.CertExpireDays, "Days,", et)
+	return ret
+}
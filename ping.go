@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	defaultPingCount   = 3
+	defaultPingTimeout = 2
+	// Protocol numbers used to parse ICMP messages
+	protocolICMP     = 1
+	protocolIPv6ICMP = 58
+)
+
+// pingID is increased for every ping so raw sockets, which receive all echo
+// replies of host, can tell their replies apart.
+var pingID uint32 = uint32(os.Getpid())
+
+type PingResult struct {
+	Address  string
+	Status   string
+	ErrorMsg string
+	Sent     int
+	Received int
+	MinRTT   time.Duration
+	AvgRTT   time.Duration
+	MaxRTT   time.Duration
+}
+
+// Loss returns packet loss ratio from 0 to 1.
+func (pr PingResult) Loss() float64 {
+	if pr.Sent == 0 {
+		return 1
+	}
+	return float64(pr.Sent-pr.Received) / float64(pr.Sent)
+}
+
+// pingAll ping addresses concurrently, result is in the same order of addrs.
+func pingAll(addrs []string, cfg *PingConfig) []PingResult {
+	ret := make([]PingResult, len(addrs))
+	var wg sync.WaitGroup
+	wg.Add(len(addrs))
+	for i, addr := range addrs {
+		go func(idx int, addr string) {
+			ret[idx] = ping(addr, cfg.GetCount(), cfg.GetTimeout())
+			wg.Done()
+		}(i, addr)
+	}
+	wg.Wait()
+	return ret
+}
+
+// listenICMP listen on unprivileged ICMP datagram socket, and fallback to
+// raw socket if it is not permitted (see net.ipv4.ping_group_range).
+func listenICMP(ip net.IP) (*icmp.PacketConn, bool, error) {
+	network, rawNetwork, address := "udp4", "ip4:icmp", "0.0.0.0"
+	if ip.To4() == nil {
+		network, rawNetwork, address = "udp6", "ip6:ipv6-icmp", "::"
+	}
+	conn, err := icmp.ListenPacket(network, address)
+	if err == nil {
+		return conn, false, nil
+	}
+	conn, rerr := icmp.ListenPacket(rawNetwork, address)
+	if rerr != nil {
+		return nil, false, fmt.Errorf("Cannot open ICMP socket: %v, %v", err, rerr)
+	}
+	return conn, true, nil
+}
+
+func ping(addr string, count int, timeout time.Duration) PingResult {
+	ret := PingResult{
+		Address: addr,
+		Status:  "Error",
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		ret.ErrorMsg = fmt.Sprintf("Invalid IP address: %s", addr)
+		return ret
+	}
+	conn, raw, err := listenICMP(ip)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	defer conn.Close()
+
+	var (
+		dst       net.Addr  = &net.UDPAddr{IP: ip}
+		echoType  icmp.Type = ipv4.ICMPTypeEcho
+		replyType icmp.Type = ipv4.ICMPTypeEchoReply
+		proto               = protocolICMP
+	)
+	if raw {
+		dst = &net.IPAddr{IP: ip}
+	}
+	if ip.To4() == nil {
+		echoType, replyType, proto = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply, protocolIPv6ICMP
+	}
+	// Kernel replaces ID of datagram sockets, so ID is only checked for raw sockets
+	id := int(atomic.AddUint32(&pingID, 1) & 0xffff)
+	var total time.Duration
+	buf := make([]byte, 1500)
+	for seq := 0; seq < count; seq++ {
+		msg := icmp.Message{
+			Type: echoType,
+			Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("domain-exporter")},
+		}
+		data, err := msg.Marshal(nil)
+		if err != nil {
+			ret.ErrorMsg = fmt.Sprintf("%v", err)
+			return ret
+		}
+		start := time.Now()
+		if _, err = conn.WriteTo(data, dst); err != nil {
+			ret.ErrorMsg = fmt.Sprintf("%v", err)
+			return ret
+		}
+		ret.Sent++
+		conn.SetReadDeadline(start.Add(timeout))
+		for {
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				// Timeout, packet is lost
+				break
+			}
+			if !sameIP(peer, ip) {
+				continue
+			}
+			reply, err := icmp.ParseMessage(proto, buf[:n])
+			if err != nil || reply.Type != replyType {
+				continue
+			}
+			echo, ok := reply.Body.(*icmp.Echo)
+			if !ok || echo.Seq != seq || (raw && echo.ID != id) {
+				continue
+			}
+			rtt := time.Since(start)
+			if ret.Received == 0 || rtt < ret.MinRTT {
+				ret.MinRTT = rtt
+			}
+			if rtt > ret.MaxRTT {
+				ret.MaxRTT = rtt
+			}
+			total += rtt
+			ret.Received++
+			break
+		}
+	}
+	if ret.Received == 0 {
+		ret.ErrorMsg = fmt.Sprintf("No echo reply from %s", addr)
+		return ret
+	}
+	ret.AvgRTT = total / time.Duration(ret.Received)
+	ret.Status = "OK"
+	return ret
+}
+
+func sameIP(addr net.Addr, ip net.IP) bool {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP.Equal(ip)
+	case *net.IPAddr:
+		return a.IP.Equal(ip)
+	default:
+		return false
+	}
+}
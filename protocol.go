@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// transportCloser close idle connections of transport, keep-alive
+// connections are not reused across checks.
+type transportCloser struct {
+	transport *http.Transport
+}
+
+func (c transportCloser) Close() error {
+	c.transport.CloseIdleConnections()
+	return nil
+}
+
+// newRequestTransport create a round tripper pinned to raddr for the HTTP
+// version of request, returned closer should be called after request done.
+func newRequestTransport(raddr string, tlsConfig *tls.Config, params *RequestParams) (http.RoundTripper, io.Closer, error) {
+	cfg := params.Config
+	dialer, err := getDialer(cfg.Proxy)
+	if err != nil {
+		return nil, nil, newRequestError("request", err)
+	}
+	switch cfg.HttpVersion {
+	case "":
+		tp := newPinnedTransport(raddr, tlsConfig, dialer)
+		return tp, transportCloser{tp}, nil
+	case "1.1":
+		tp := newPinnedTransport(raddr, tlsConfig, dialer)
+		// Non-nil empty map disables HTTP/2
+		tp.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		return tp, transportCloser{tp}, nil
+	case "2":
+		if !params.Https {
+			return nil, nil, newRequestError("protocol", fmt.Errorf("HTTP/2 requires https"))
+		}
+		tp := newPinnedTransport(raddr, tlsConfig, dialer)
+		tp.ForceAttemptHTTP2 = true
+		return tp, transportCloser{tp}, nil
+	case "3":
+		if !params.Https {
+			return nil, nil, newRequestError("protocol", fmt.Errorf("HTTP/3 requires https"))
+		}
+		// QUIC is UDP based, config with proxy is rejected when loaded
+		port := ""
+		if cfg.Http3Discovery == "alt-svc" {
+			if port, err = discoverHttp3Port(raddr, tlsConfig, dialer, params); err != nil {
+				return nil, nil, err
+			}
+		}
+		tp := newPinnedHttp3Transport(raddr, port, tlsConfig)
+		return tp, tp, nil
+	default:
+		return nil, nil, newRequestError("protocol", fmt.Errorf("Unsupported HTTP version: %s", cfg.HttpVersion))
+	}
+}
+
+// newPinnedHttp3Transport create a HTTP/3 transport that always dial to raddr,
+// port overrides the port of request URL when not empty.
+func newPinnedHttp3Transport(raddr, port string, tlsConfig *tls.Config) *http3.Transport {
+	return &http3.Transport{
+		TLSClientConfig: tlsConfig,
+		Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+			_, aport, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			if port != "" {
+				aport = port
+			}
+			return quic.DialAddrEarly(ctx, net.JoinHostPort(raddr, aport), tlsCfg, cfg)
+		},
+	}
+}
+
+// discoverHttp3Port request over TCP and returns HTTP/3 port in Alt-Svc header.
+func discoverHttp3Port(raddr string, tlsConfig *tls.Config, dialer Dialer, params *RequestParams) (string, error) {
+	url := fmt.Sprintf("https://%s%s", params.Host, params.Path)
+	req, err := http.NewRequest("HEAD", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Host = params.Host
+	client := &http.Client{
+		Transport: newPinnedTransport(raddr, tlsConfig, dialer),
+		Timeout:   10 * time.Second,
+	}
+	defer client.CloseIdleConnections()
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	port, ok := parseAltSvcHttp3(resp.Header.Values("Alt-Svc"))
+	if !ok {
+		return "", newRequestError("protocol", fmt.Errorf("HTTP/3 not advertised in Alt-Svc"))
+	}
+	return port, nil
+}
+
+// parseAltSvcHttp3 returns port of h3 alternative service, such as
+// h3=":443"; ma=86400. Alternatives on other hosts are ignored.
+func parseAltSvcHttp3(values []string) (string, bool) {
+	for _, value := range values {
+		for _, alt := range strings.Split(value, ",") {
+			kv := strings.SplitN(strings.TrimSpace(strings.SplitN(alt, ";", 2)[0]), "=", 2)
+			if len(kv) != 2 || kv[0] != "h3" {
+				continue
+			}
+			host, port, err := net.SplitHostPort(strings.Trim(kv[1], `"`))
+			if err != nil || host != "" {
+				continue
+			}
+			return port, true
+		}
+	}
+	return "", false
+}
+
+// checkProtocol verify negotiated protocol of response matches HTTP version
+// of request.
+func checkProtocol(resp *http.Response, version string) error {
+	switch version {
+	case "1.1", "2", "3":
+		expect := fmt.Sprintf("HTTP/%s", version)
+		if version != "1.1" {
+			expect += ".0"
+		}
+		if resp.Proto != expect {
+			return newRequestError("protocol", fmt.Errorf("Protocol %s not equals to %s", resp.Proto, expect))
+		}
+	}
+	return nil
+}
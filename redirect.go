@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Same as the limit of http.Client
+const maxRedirects = 10
+
+type RedirectConfig struct {
+	// Regex that final URL after redirects must match
+	FinalURL string `yaml:"final_url"`
+	// Check HTTP request of same host and path redirects to HTTPS
+	HttpsRedirect bool `yaml:"https_redirect"`
+}
+
+func (r *RedirectConfig) Validate() error {
+	if r == nil || r.FinalURL == "" {
+		return nil
+	}
+	_, err := regexp.Compile(r.FinalURL)
+	return err
+}
+
+// CheckFinalURL returns redirect error if url not match final_url pattern.
+func (r *RedirectConfig) CheckFinalURL(url string) error {
+	if r == nil || r.FinalURL == "" {
+		return nil
+	}
+	re, err := regexp.Compile(r.FinalURL)
+	if err != nil {
+		return newRequestError("request", err)
+	}
+	if !re.MatchString(url) {
+		return newRequestError("redirect", fmt.Errorf("Final URL %s not match %q", url, r.FinalURL))
+	}
+	return nil
+}
+
+type RedirectHop struct {
+	URL        string
+	StatusCode int
+	Location   string
+}
+
+func newRedirectHop(resp *http.Response) RedirectHop {
+	return RedirectHop{
+		URL:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		Location:   resp.Header.Get("Location"),
+	}
+}
+
+// checkRedirectLoop stops redirects when a URL is visited twice or there are
+// too many redirects.
+func checkRedirectLoop(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return newRequestError("redirect", fmt.Errorf("Stopped after %d redirects", len(via)))
+	}
+	url := req.URL.String()
+	for _, prev := range via {
+		if prev.URL.String() == url {
+			return newRequestError("redirect", fmt.Errorf("Redirect loop detected at %s", url))
+		}
+	}
+	return nil
+}
+
+// checkHttpsRedirect request HTTP URL of params through raddr and check it
+// redirects to HTTPS.
+func checkHttpsRedirect(raddr string, params *RequestParams) error {
+	dialer, err := getDialer(params.Config.Proxy)
+	if err != nil {
+		return newRequestError("request", err)
+	}
+	// Port of host is the HTTPS one, so default HTTP port is used
+	url := fmt.Sprintf("http://%s%s", hostname(params.Host), params.Path)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return newRequestError("request", err)
+	}
+	client := &http.Client{
+		Transport: newPinnedTransport(raddr, nil, dialer),
+		Timeout:   10 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	defer client.CloseIdleConnections()
+	resp, err := client.Do(req)
+	if err != nil {
+		return newRequestError("redirect", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return newRequestError("redirect", fmt.Errorf("HTTP status %v is not a redirect", resp.StatusCode))
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return newRequestError("redirect", err)
+	}
+	if location.Scheme != "https" {
+		return newRequestError("redirect", fmt.Errorf("HTTP redirects to %s instead of HTTPS", location))
+	}
+	// Redirect must keep the host and path, otherwise the HTTPS page is not
+	// the requested one
+	if !strings.EqualFold(location.Hostname(), req.URL.Hostname()) || redirectPath(location.Path) != redirectPath(req.URL.Path) {
+		return newRequestError("redirect", fmt.Errorf("HTTP redirects to %s instead of HTTPS of %s", location, url))
+	}
+	return nil
+}
+
+// redirectPath returns path of URL, empty path is same as "/".
+func redirectPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// HSTSPolicy is parsed Strict-Transport-Security header, see RFC 6797.
+type HSTSPolicy struct {
+	Present           bool
+	MaxAge            int64
+	IncludeSubDomains bool
+	Preload           bool
+}
+
+func parseHSTS(value string) HSTSPolicy {
+	ret := HSTSPolicy{}
+	if value == "" {
+		return ret
+	}
+	ret.Present = true
+	for _, directive := range strings.Split(value, ";") {
+		kv := strings.SplitN(strings.TrimSpace(directive), "=", 2)
+		switch strings.ToLower(kv[0]) {
+		case "max-age":
+			if len(kv) == 2 {
+				age, err := strconv.ParseInt(strings.Trim(strings.TrimSpace(kv[1]), `"`), 10, 64)
+				if err == nil {
+					ret.MaxAge = age
+				}
+			}
+		case "includesubdomains":
+			ret.IncludeSubDomains = true
+		case "preload":
+			ret.Preload = true
+		}
+	}
+	return ret
+}
@@ -3,10 +3,17 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -18,20 +25,159 @@ type RequestResult struct {
 	Path       string
 	Address    string
 	StatusCode int
+	IPFamily   string
 	ErrorMsg   string
+	Reason     string
+	// Reasons of failed assertions
+	Assertions []string
+	Protocol   string
+	Timing     RequestTiming
+	// Hash of normalized response body when content_hash is set
+	ContentHash string
+	// Redirects followed, FinalURL is URL of the last response
+	Redirects []RedirectHop
+	FinalURL  string
+	// HSTS policy of HTTPS response
+	HSTS *HSTSPolicy
+	// Result of https_redirect check, nil when it is not checked
+	HttpsRedirect *bool
+	// Compliance of each security header when security_headers is set
+	SecurityHeaders map[string]bool
+	// Assets of HTML page when assets is set
+	Assets []AssetResult
+	// Certificate expiry of HTTPS request
+	CertExpireAt   time.Time
+	CertExpireDays int
+}
+
+// RequestError is an error with a bounded reason used as metric label.
+type RequestError struct {
+	Reason string
+	Err    error
+}
+
+func (e *RequestError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+func newRequestError(reason string, err error) *RequestError {
+	return &RequestError{
+		Reason: reason,
+		Err:    err,
+	}
+}
+
+// requestErrorReason classify err into one of dns, no_address, request, auth,
+// token, timeout, connect, tls, http, protocol, status, redirect, body or an
+// assertion reason.
+func requestErrorReason(err error) string {
+	var (
+		assertErr AssertionErrors
+		reqErr    *RequestError
+		netErr    net.Error
+		opErr     *net.OpError
+		tlsErr    tls.RecordHeaderError
+		alertErr  tls.AlertError
+		verifyErr *tls.CertificateVerificationError
+		certErr   x509.CertificateInvalidError
+		authErr   x509.UnknownAuthorityError
+		hostErr   x509.HostnameError
+	)
+	switch {
+	case errors.As(err, &assertErr) && len(assertErr) > 0:
+		return assertErr[0].Reason
+	case errors.As(err, &reqErr):
+		return reqErr.Reason
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &tlsErr), errors.As(err, &alertErr), errors.As(err, &verifyErr),
+		errors.As(err, &certErr), errors.As(err, &authErr), errors.As(err, &hostErr):
+		return "tls"
+	case errors.As(err, &opErr) && opErr.Op == "remote error":
+		// Alert sent by server over TCP
+		return "tls"
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return "connect"
+	default:
+		return "http"
+	}
+}
+
+// RequestTiming records duration of each request phase and the served
+// certificate, when redirects are followed they are of the last hop.
+type RequestTiming struct {
+	DNS      time.Duration
+	Connect  time.Duration
+	TLS      time.Duration
+	TTFB     time.Duration
+	Transfer time.Duration
+	Total    time.Duration
+	// Time to fetch page assets, it is not included in Transfer and Total
+	Assets time.Duration
+	// Certificate served by the address, nil for HTTP
+	Certificate *x509.Certificate
+}
+
+func (t *RequestTiming) clientTrace() *httptrace.ClientTrace {
+	var connectStart, tlsStart, wroteRequest time.Time
+	return &httptrace.ClientTrace{
+		ConnectStart: func(network, addr string) {
+			connectStart = time.Now()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.Connect = time.Since(connectStart)
+		},
+		TLSHandshakeStart: func() {
+			tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(cs tls.ConnectionState, err error) {
+			t.TLS = time.Since(tlsStart)
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			t.TTFB = time.Since(wroteRequest)
+		},
+	}
+}
+
+// capturePeerCertificate returns a copy of cfg that calls capture with leaf
+// certificate served by peer after it is verified. Certificate that fails
+// verification is in the CertificateVerificationError of handshake.
+func capturePeerCertificate(cfg *tls.Config, capture func(*x509.Certificate)) *tls.Config {
+	ret := cfg.Clone()
+	ret.VerifyConnection = func(cs tls.ConnectionState) error {
+		if cert, err := getLeafCertificate(cs); err == nil {
+			capture(cert)
+		}
+		if cfg.VerifyConnection != nil {
+			return cfg.VerifyConnection(cs)
+		}
+		return nil
+	}
+	return ret
 }
 
 type RequestParams struct {
-	Domain string
-	Host   string
-	Path   string
-	Https  bool
+	Domain   string
+	Host     string
+	Path     string
+	Https    bool
+	IPFamily string
+	Config   *RequestConfig
 }
 
 type RequestResults map[string]RequestResult
 
 type RequestChecker struct {
 	Domains []RequestConfig
+	// Default ip_protocol of requests
+	IPProtocol string
 }
 
 func NewRequestChecker(domains []RequestConfig) *RequestChecker {
@@ -47,14 +193,23 @@ func (rc *RequestChecker) Check() RequestResults {
 		ret  RequestResults = make(RequestResults)
 	)
 	domains := []*RequestParams{}
-	for _, cfg := range rc.Domains {
+	for i := range rc.Domains {
+		cfg := &rc.Domains[i]
+		protocol := cfg.IPProtocol
+		if protocol == "" {
+			protocol = rc.IPProtocol
+		}
 		for _, domain := range cfg.Domains {
-			domains = append(domains, &RequestParams{
-				Domain: domain,
-				Host:   cfg.Host,
-				Path:   cfg.Path,
-				Https:  cfg.Https,
-			})
+			for _, family := range ipFamilies(protocol) {
+				domains = append(domains, &RequestParams{
+					Domain:   domain,
+					Host:     cfg.Host,
+					Path:     cfg.Path,
+					Https:    cfg.Https,
+					IPFamily: family,
+					Config:   cfg,
+				})
+			}
 		}
 	}
 	wg.Add(len(domains))
@@ -63,7 +218,7 @@ func (rc *RequestChecker) Check() RequestResults {
 			rr := rc.CheckOneDomain(params)
 			lock.Lock()
 			key := fmt.Sprintf("%s @ %s", params.Host, params.Domain)
-			ret[key] = rr
+			ret[familyKey(key, params.IPFamily)] = rr
 			lock.Unlock()
 			if rr.ErrorMsg != "" {
 				log.Printf("RequestChecker Error: %s: %s%s -> %v", params.Domain, params.Host, params.Path, rr.ErrorMsg)
@@ -83,29 +238,63 @@ func (rc *RequestChecker) CheckOneDomain(params *RequestParams) RequestResult {
 		Path:       params.Path,
 		Address:    "",
 		StatusCode: 0,
+		IPFamily:   params.IPFamily,
 	}
-	addrs, err := net.LookupHost(params.Domain)
+	start := time.Now()
+	addrs, err := lookupHostFamily(params.Domain, params.IPFamily)
+	ret.Timing.DNS = time.Since(start)
 	if err != nil {
 		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		ret.Reason = "dns"
 		return ret
 	}
 	if len(addrs) == 0 {
 		ret.ErrorMsg = "Domain has no IP addresses"
+		ret.Reason = "no_address"
 		return ret
 	}
 	addr := selectAddress(addrs)
 	ret.Address = addr
-	responseOk, statusCode, err := rc.doRequest(addr, params)
+	responseOk, statusCode, err := rc.doRequest(addr, params, &ret)
+	ret.Timing.Total = time.Since(start) - ret.Timing.Assets
+	if responseOk && params.Config.Redirect != nil && params.Config.Redirect.HttpsRedirect && params.Https {
+		// Result is only exported by its own metric, status of request is
+		// not changed by it
+		rerr := checkHttpsRedirect(addr, params)
+		if rerr != nil {
+			log.Printf("RequestChecker HTTPS Redirect Error: %s: %s%s -> %v", params.Domain, params.Host, params.Path, rerr)
+		}
+		redirected := rerr == nil
+		ret.HttpsRedirect = &redirected
+	}
 	if err != nil {
 		ret.ErrorMsg = fmt.Sprintf("%v", err)
 	}
+	if cert := ret.Timing.Certificate; cert != nil {
+		ret.CertExpireAt = cert.NotAfter
+		ret.CertExpireDays = expireDays(cert.NotAfter)
+	}
 	if responseOk {
 		ret.Status = "OK"
+	} else if err != nil {
+		var assertErr AssertionErrors
+		ret.Reason = requestErrorReason(err)
+		if errors.As(err, &assertErr) {
+			ret.Assertions = assertErr.Reasons()
+		}
 	}
 	ret.StatusCode = statusCode
 	return ret
 }
 
+// hostname returns host without port.
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
 func selectAddress(addrs []string) string {
 	for _, addr := range addrs {
 		ip := net.ParseIP(addr)
@@ -121,48 +310,234 @@ func selectAddress(addrs []string) string {
 	return addrs[0]
 }
 
-func (rc *RequestChecker) doRequest(raddr string, params *RequestParams) (bool, int, error) {
+// newPinnedTransport create a transport that always dial to raddr no matter
+// what the request host is.
+func newPinnedTransport(raddr string, tlsConfig *tls.Config, dialer Dialer) *http.Transport {
+	return &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			_, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			return dialer.DialContext(ctx, network, net.JoinHostPort(raddr, port))
+		},
+		TLSClientConfig: tlsConfig,
+	}
+}
+
+func (rc *RequestChecker) doRequest(raddr string, params *RequestParams, result *RequestResult) (bool, int, error) {
 	var url string
 	if params.Https {
 		url = fmt.Sprintf("https://%s%s", params.Host, params.Path)
 	} else {
 		url = fmt.Sprintf("http://%s%s", params.Host, params.Path)
 	}
+	cfg := params.Config
+	body, err := cfg.GetBody()
+	if err != nil {
+		return false, 0, newRequestError("request", err)
+	}
 	// Generate request
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequest(cfg.GetMethod(), url, body)
 	if err != nil {
-		return false, 0, err
+		return false, 0, newRequestError("request", err)
+	}
+	timing := &result.Timing
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), timing.clientTrace()))
+	// Handshake errors of crypto/tls are mostly untyped, so they are
+	// recorded by trace to classify error of request
+	var (
+		handshakeLock sync.Mutex
+		handshakeErr  error
+	)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		TLSHandshakeDone: func(cs tls.ConnectionState, err error) {
+			handshakeLock.Lock()
+			handshakeErr = err
+			handshakeLock.Unlock()
+		},
+	}))
+	handshakeFailed := func() bool {
+		handshakeLock.Lock()
+		defer handshakeLock.Unlock()
+		return handshakeErr != nil
 	}
 	req.Host = params.Host
 	req.Header.Add("Host", params.Host)
+	for key, value := range cfg.Headers {
+		req.Header.Set(key, value)
+	}
+	if cfg.Auth != nil {
+		dialer, err := getDialer(cfg.Proxy)
+		if err != nil {
+			return false, 0, newRequestError("request", err)
+		}
+		if err = cfg.Auth.Authorize(req, dialer); err != nil {
+			return false, 0, err
+		}
+	}
+
+	tlsConfig, err := cfg.GetTLSConfig()
+	if err != nil {
+		return false, 0, newRequestError("request", err)
+	}
+	// Certificate is captured in handshake so HTTP/3 is covered too, the last
+	// one served is kept so it is of the last hop
+	var (
+		peerLock sync.Mutex
+		peerCert *x509.Certificate
+	)
+	capture := func(cert *x509.Certificate) {
+		peerLock.Lock()
+		peerCert = cert
+		peerLock.Unlock()
+	}
+	defer func() {
+		peerLock.Lock()
+		timing.Certificate = peerCert
+		peerLock.Unlock()
+	}()
+	tlsConfig = capturePeerCertificate(tlsConfig, capture)
 
 	// Prepare for http client
-	tp := &http.Transport{
-		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-			dialer := net.Dialer{}
-			_, port, err := net.SplitHostPort(addr)
-			if err != nil {
-				return nil, err
-			}
-			return dialer.DialContext(ctx, network, fmt.Sprintf("%s:%s", raddr, port))
-		},
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	tp, closer, err := newRequestTransport(raddr, tlsConfig, params)
+	if err != nil {
+		return false, 0, err
 	}
+	defer closer.Close()
 	client := &http.Client{
 		Transport: tp,
 		Timeout:   10 * time.Second,
 	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		result.Redirects = append(result.Redirects, newRedirectHop(req.Response))
+		if !cfg.IsFollowRedirects() {
+			return http.ErrUseLastResponse
+		}
+		return checkRedirectLoop(req, via)
+	}
 	resp, err := client.Do(req)
 	if err != nil {
+		var (
+			netErr    net.Error
+			verifyErr *tls.CertificateVerificationError
+		)
+		if errors.As(err, &verifyErr) {
+			// Expiry is still known when certificate fails verification
+			if cert, err := getLeafCertificate(tls.ConnectionState{PeerCertificates: verifyErr.UnverifiedCertificates}); err == nil {
+				capture(cert)
+			}
+		}
+		if handshakeFailed() && !(errors.As(err, &netErr) && netErr.Timeout()) {
+			return false, 0, newRequestError("tls", err)
+		}
 		return false, 0, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode == 200 {
-		buf := make([]byte, 1)
-		_, err := resp.Body.Read(buf)
-		return true, 200, err
+	result.FinalURL = resp.Request.URL.String()
+	if resp.TLS != nil {
+		hsts := parseHSTS(resp.Header.Get("Strict-Transport-Security"))
+		result.HSTS = &hsts
 	}
-	return false, resp.StatusCode, fmt.Errorf("Status not equals to 200, %v", resp.StatusCode)
+	if cfg.SecurityHeaders {
+		result.SecurityHeaders = auditSecurityHeaders(resp.Header, resp.TLS != nil)
+	}
+	transferStart := time.Now()
+	defer func() {
+		// Drain body to measure transfer time
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxAssertionBodySize))
+		resp.Body.Close()
+		timing.Transfer = time.Since(transferStart) - timing.Assets
+	}()
+	result.Protocol = resp.Proto
+	if resp.StatusCode == http.StatusUnauthorized {
+		// Token may be revoked, fetch a new one next time
+		cfg.Auth.Unauthorized()
+	}
+	if err = checkProtocol(resp, cfg.HttpVersion); err != nil {
+		return false, resp.StatusCode, err
+	}
+	if !matchStatusCode(resp.StatusCode, cfg.GetExpectStatus()) {
+		return false, resp.StatusCode, newRequestError("status", fmt.Errorf("Status %v not in expected %v", resp.StatusCode, cfg.GetExpectStatus()))
+	}
+	if err = cfg.Redirect.CheckFinalURL(result.FinalURL); err != nil {
+		return false, resp.StatusCode, err
+	}
+	if !cfg.Assertions.IsEmpty() || cfg.ContentHash != nil || cfg.Assets != nil {
+		body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxAssertionBodySize))
+		if err != nil {
+			return false, resp.StatusCode, newRequestError("body", err)
+		}
+		if cfg.ContentHash != nil {
+			result.ContentHash = cfg.ContentHash.Hash(body)
+		}
+		if cfg.Assets != nil && strings.Contains(resp.Header.Get("Content-Type"), "html") {
+			dialer, err := getDialer(cfg.Proxy)
+			if err != nil {
+				return false, resp.StatusCode, newRequestError("request", err)
+			}
+			assetStart := time.Now()
+			result.Assets = cfg.Assets.checkAssets(body, resp.Request.URL, &assetTransport{
+				host:   hostname(params.Host),
+				pinned: tp,
+				other: &http.Transport{
+					DialContext:       dialer.DialContext,
+					TLSClientConfig:   tlsConfig,
+					ForceAttemptHTTP2: true,
+				},
+			})
+			timing.Assets = time.Since(assetStart)
+		}
+		if !cfg.Assertions.IsEmpty() {
+			if err = cfg.Assertions.Assert(resp.Header, body); err != nil {
+				return false, resp.StatusCode, err
+			}
+		}
+		return true, resp.StatusCode, nil
+	}
+	buf := make([]byte, 1)
+	_, err = resp.Body.Read(buf)
+	if err == io.EOF {
+		// Empty body is fine, such as 204 or 301
+		err = nil
+	}
+	return true, resp.StatusCode, err
+}
+
+// matchStatusCode check code against expects like "200", "2xx" or "200-299".
+func matchStatusCode(code int, expects []string) bool {
+	for _, expect := range expects {
+		low, high, err := parseExpectStatus(expect)
+		if err == nil && code >= low && code <= high {
+			return true
+		}
+	}
+	return false
+}
+
+// parseExpectStatus returns range of status codes of expect, such as 200,
+// 2xx or 200-299.
+func parseExpectStatus(expect string) (int, int, error) {
+	expect = strings.ToLower(strings.TrimSpace(expect))
+	if len(expect) == 3 && strings.HasSuffix(expect, "xx") {
+		class, err := strconv.Atoi(expect[:1])
+		if err != nil || class < 1 || class > 5 {
+			return 0, 0, fmt.Errorf("Invalid expect status: %s", expect)
+		}
+		return class * 100, class*100 + 99, nil
+	}
+	if parts := strings.SplitN(expect, "-", 2); len(parts) == 2 {
+		low, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+		high, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err1 != nil || err2 != nil || low < 100 || high > 599 || low > high {
+			return 0, 0, fmt.Errorf("Invalid expect status: %s", expect)
+		}
+		return low, high, nil
+	}
+	value, err := strconv.Atoi(expect)
+	if err != nil || value < 100 || value > 599 {
+		return 0, 0, fmt.Errorf("Invalid expect status: %s", expect)
+	}
+	return value, value, nil
 }
 
 func (rc *RequestChecker) RequestHttp(addr string, params *RequestParams) (bool, int, error) {
@@ -179,9 +554,20 @@ func (rc *RequestChecker) RequestHttp(addr string, params *RequestParams) (bool,
 	}
 	req.Host = params.Host
 	req.Header.Add("Host", params.Host)
+	tlsConfig, err := params.Config.GetTLSConfig()
+	if err != nil {
+		return false, 0, err
+	}
+	// URL host is an address, so SNI name should be set explicitly
+	tlsConfig.ServerName = hostname(params.Host)
+	dialer, err := getDialer(params.Config.Proxy)
+	if err != nil {
+		return false, 0, err
+	}
 	client := &http.Client{
 		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			DialContext:     dialer.DialContext,
+			TLSClientConfig: tlsConfig,
 		},
 		Timeout: 15 * time.Second,
 	}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// startTLSServer start HTTPS server on loopback with tlsConfig, returns its
+// host and port.
+func startTLSServer(t *testing.T, tlsConfig *tls.Config) string {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	srv.TLS = tlsConfig
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return strings.TrimPrefix(srv.URL, "https://")
+}
+
+func checkRequest(host string) RequestResult {
+	return checkRequestConfig(host, &RequestConfig{InsecureSkipVerify: true})
+}
+
+func checkRequestConfig(host string, cfg *RequestConfig) RequestResult {
+	return NewRequestChecker(nil).CheckOneDomain(&RequestParams{
+		Domain: "127.0.0.1",
+		Host:   host,
+		Path:   "/",
+		Https:  true,
+		Config: cfg,
+	})
+}
+
+func TestRequestErrorReasonRemoteAlert(t *testing.T) {
+	host := startTLSServer(t, &tls.Config{MinVersion: tls.VersionTLS13})
+	conn, err := tls.Dial("tcp", host, &tls.Config{
+		InsecureSkipVerify: true,
+		MaxVersion:         tls.VersionTLS12,
+	})
+	if err == nil {
+		conn.Close()
+		t.Fatal("Expect TLS version mismatch error")
+	}
+	if !strings.Contains(err.Error(), "remote error") {
+		t.Fatalf("Expect alert sent by server, got %v", err)
+	}
+	if reason := requestErrorReason(err); reason != "tls" {
+		t.Errorf("Expect tls reason of %v, got %s", err, reason)
+	}
+}
+
+func TestRequestTLSVersionMismatch(t *testing.T) {
+	// Server selects TLS 1.0 that client does not support, so the handshake
+	// error is generated by client
+	host := startTestServer(t, func(conn net.Conn) {
+		buf := make([]byte, 4096)
+		if _, err := conn.Read(buf); err != nil {
+			return
+		}
+		hello := []byte{0x03, 0x01}
+		hello = append(hello, make([]byte, 32)...)
+		// Session ID, cipher suite and compression method
+		hello = append(hello, 0x00, 0x00, 0x2f, 0x00)
+		msg := append([]byte{0x02, 0x00, 0x00, byte(len(hello))}, hello...)
+		conn.Write(append([]byte{0x16, 0x03, 0x01, 0x00, byte(len(msg))}, msg...))
+		conn.Read(buf)
+	})
+	ret := checkRequest(host)
+	if ret.Status != "Error" {
+		t.Fatal("Expect error of TLS version mismatch")
+	}
+	if !strings.Contains(ret.ErrorMsg, "unsupported protocol version") {
+		t.Fatalf("Expect unsupported version error, got %s", ret.ErrorMsg)
+	}
+	if ret.Reason != "tls" {
+		t.Errorf("Expect tls reason of %s, got %s", ret.ErrorMsg, ret.Reason)
+	}
+}
+
+func TestRequestTLS(t *testing.T) {
+	cert, _ := newTestCertificate(t, "request.test")
+	host := startTLSServer(t, &tls.Config{Certificates: []tls.Certificate{cert}})
+	ret := checkRequest(host)
+	if ret.Status != "OK" {
+		t.Fatalf("Request failed: %s", ret.ErrorMsg)
+	}
+	if ret.Timing.Certificate == nil {
+		t.Error("Expect served certificate recorded")
+	}
+}
+
+func TestRequestUnverifiedCertificate(t *testing.T) {
+	cert, _ := newTestCertificate(t, "request.test")
+	host := startTLSServer(t, &tls.Config{Certificates: []tls.Certificate{cert}})
+	ret := checkRequestConfig(host, &RequestConfig{})
+	if ret.Status != "Error" || ret.Reason != "tls" {
+		t.Fatalf("Expect tls error of unknown authority, got %s %s", ret.Status, ret.Reason)
+	}
+	if ret.Timing.Certificate == nil || ret.CertExpireAt.IsZero() {
+		t.Error("Expect certificate failing verification recorded")
+	}
+}
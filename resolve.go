@@ -1,22 +1,152 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"sync"
+	"time"
 )
 
+const dnsTimeout = 5 * time.Second
+
+// resolver is shared by all DNS based checkers.
+var resolver = &net.Resolver{}
+
+func lookupHost(domain string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+	defer cancel()
+	return resolver.LookupHost(ctx, domain)
+}
+
+// lookupHostFamily resolve domain to addresses of family, family is ip4, ip6
+// or empty for both.
+func lookupHostFamily(domain, family string) ([]string, error) {
+	if family == "" {
+		return lookupHost(domain)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+	defer cancel()
+	ips, err := resolver.LookupIP(ctx, family, domain)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		ret = append(ret, ip.String())
+	}
+	return ret, nil
+}
+
+// ipFamilies returns address families to check for ip_protocol setting,
+// empty family means system default.
+func ipFamilies(protocol string) []string {
+	switch protocol {
+	case "ip4", "ip6":
+		return []string{protocol}
+	case "both":
+		return []string{"ip4", "ip6"}
+	default:
+		return []string{""}
+	}
+}
+
+func validateIPProtocol(protocol string) error {
+	switch protocol {
+	case "", "ip4", "ip6", "both":
+		return nil
+	default:
+		return fmt.Errorf("Invalid ip_protocol: %s, should be ip4, ip6 or both", protocol)
+	}
+}
+
+// ipVersionLabel returns value of ip_version label, any means the address is
+// selected by system default.
+func ipVersionLabel(family string) string {
+	switch family {
+	case "ip4":
+		return "4"
+	case "ip6":
+		return "6"
+	default:
+		return "any"
+	}
+}
+
+// singleFamily returns family of ip_protocol for checks that are not checked
+// per family, empty for both or system default.
+func singleFamily(protocol string) string {
+	switch protocol {
+	case "ip4", "ip6":
+		return protocol
+	default:
+		return ""
+	}
+}
+
+// filterFamily returns addresses of family, all addresses for empty family.
+func filterFamily(addrs []string, family string) []string {
+	if family == "" {
+		return addrs
+	}
+	ret := []string{}
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		if ip != nil && (ip.To4() != nil) == (family == "ip4") {
+			ret = append(ret, addr)
+		}
+	}
+	return ret
+}
+
+// tcpNetwork returns network name for dialing TCP in family.
+func tcpNetwork(family string) string {
+	switch family {
+	case "ip4":
+		return "tcp4"
+	case "ip6":
+		return "tcp6"
+	default:
+		return "tcp"
+	}
+}
+
+// familyKey returns result key of target in family.
+func familyKey(key, family string) string {
+	if family == "" {
+		return key
+	}
+	return fmt.Sprintf("%s @ %s", family, key)
+}
+
+func lookupMX(domain string) ([]*net.MX, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+	defer cancel()
+	return resolver.LookupMX(ctx, domain)
+}
+
+func lookupTXT(domain string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+	defer cancel()
+	return resolver.LookupTXT(ctx, domain)
+}
+
 type ResolveResult struct {
 	Domain   string
+	IPFamily string
 	Status   string
 	IPs      []string
 	ErrorMsg string
+	Pings    []PingResult
 }
 
 type ResolveResults map[string]ResolveResult
 
 type ResolveChecker struct {
-	Domains []string
+	Domains    []string
+	IPProtocol string
+	// Ping resolved addresses when it is not nil
+	Ping *PingConfig
 }
 
 func NewResolveChecker(domains []string) *ResolveChecker {
@@ -31,29 +161,33 @@ func (rc *ResolveChecker) Check() ResolveResults {
 		wg   sync.WaitGroup
 		ret  ResolveResults = make(ResolveResults)
 	)
-	wg.Add(len(rc.Domains))
+	families := ipFamilies(rc.IPProtocol)
+	wg.Add(len(rc.Domains) * len(families))
 	for _, item := range rc.Domains {
-		go func(domain string) {
-			rr := rc.CheckOneDomain(domain)
-			lock.Lock()
-			ret[domain] = rr
-			lock.Unlock()
-			wg.Done()
-		}(item)
+		for _, family := range families {
+			go func(domain, family string) {
+				rr := rc.CheckOneDomain(domain, family)
+				lock.Lock()
+				ret[familyKey(domain, family)] = rr
+				lock.Unlock()
+				wg.Done()
+			}(item, family)
+		}
 	}
 	wg.Wait()
 	return ret
 }
 
-func (rc *ResolveChecker) CheckOneDomain(domain string) ResolveResult {
+func (rc *ResolveChecker) CheckOneDomain(domain, family string) ResolveResult {
 	ret := ResolveResult{
 		Domain:   domain,
+		IPFamily: family,
 		Status:   "Error",
 		IPs:      []string{},
 		ErrorMsg: "",
 	}
 
-	addrs, err := net.LookupHost(domain)
+	addrs, err := lookupHostFamily(domain, family)
 	if err != nil {
 		ret.ErrorMsg = fmt.Sprintf("%v", err)
 	}
@@ -61,5 +195,8 @@ func (rc *ResolveChecker) CheckOneDomain(domain string) ResolveResult {
 	if len(addrs) > 0 {
 		ret.Status = "OK"
 	}
+	if rc.Ping != nil {
+		ret.Pings = pingAll(addrs, rc.Ping)
+	}
 	return ret
 }
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var (
+	cspDirectiveRe          = regexp.MustCompile(`^[a-zA-Z0-9-]+$`)
+	permissionsPolicyRe     = regexp.MustCompile(`^[a-z0-9-]+=(\*|\(.*\)|[a-z]+|"[^"]*")$`)
+	compliantReferrerPolicy = map[string]bool{
+		"no-referrer":                     true,
+		"no-referrer-when-downgrade":      true,
+		"same-origin":                     true,
+		"origin":                          true,
+		"strict-origin":                   true,
+		"origin-when-cross-origin":        true,
+		"strict-origin-when-cross-origin": true,
+	}
+)
+
+// auditSecurityHeaders check response headers against common security
+// practices, returns compliance of each header.
+func auditSecurityHeaders(header http.Header, https bool) map[string]bool {
+	csp := parseCSP(header.Get("Content-Security-Policy"))
+	_, frameAncestors := csp["frame-ancestors"]
+	frameOptions := strings.ToUpper(strings.TrimSpace(header.Get("X-Frame-Options")))
+	return map[string]bool{
+		"content_security_policy": len(csp) > 0,
+		"x_content_type_options":  strings.EqualFold(strings.TrimSpace(header.Get("X-Content-Type-Options")), "nosniff"),
+		"frame_options":           frameAncestors || frameOptions == "DENY" || frameOptions == "SAMEORIGIN",
+		"referrer_policy":         checkReferrerPolicy(header.Get("Referrer-Policy")),
+		"permissions_policy":      checkPermissionsPolicy(header.Get("Permissions-Policy")),
+		"cookies":                 checkCookies(header, https),
+	}
+}
+
+// securityScore returns ratio of compliant headers from 0 to 1.
+func securityScore(audit map[string]bool) float64 {
+	if len(audit) == 0 {
+		return 0
+	}
+	compliant := 0
+	for _, ok := range audit {
+		if ok {
+			compliant++
+		}
+	}
+	return float64(compliant) / float64(len(audit))
+}
+
+// parseCSP returns directives of policy, nil if policy is empty or has a
+// malformed directive.
+func parseCSP(policy string) map[string]string {
+	ret := make(map[string]string)
+	for _, directive := range strings.Split(policy, ";") {
+		// Name and values may be separated by any ASCII whitespace
+		fields := strings.Fields(directive)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		if !cspDirectiveRe.MatchString(name) {
+			return nil
+		}
+		value := strings.Join(fields[1:], " ")
+		// Only the first directive of a name is used, see CSP level 3 section 2.2.1
+		if _, ok := ret[name]; !ok {
+			ret[name] = value
+		}
+	}
+	return ret
+}
+
+// checkReferrerPolicy check the last recognized policy is not unsafe-url.
+func checkReferrerPolicy(value string) bool {
+	ret := false
+	for _, policy := range strings.Split(value, ",") {
+		policy = strings.ToLower(strings.TrimSpace(policy))
+		if policy == "unsafe-url" {
+			ret = false
+		} else if compliantReferrerPolicy[policy] {
+			ret = true
+		}
+	}
+	return ret
+}
+
+func checkPermissionsPolicy(value string) bool {
+	if strings.TrimSpace(value) == "" {
+		return false
+	}
+	for _, member := range strings.Split(value, ",") {
+		if !permissionsPolicyRe.MatchString(strings.TrimSpace(member)) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkCookies check all cookies have HttpOnly, SameSite and Secure (for
+// HTTPS) attributes, no cookie is compliant.
+func checkCookies(header http.Header, https bool) bool {
+	for _, line := range header.Values("Set-Cookie") {
+		var secure, httpOnly, sameSite bool
+		for _, attr := range strings.Split(line, ";")[1:] {
+			name := strings.ToLower(strings.TrimSpace(strings.SplitN(attr, "=", 2)[0]))
+			switch name {
+			case "secure":
+				secure = true
+			case "httponly":
+				httpOnly = true
+			case "samesite":
+				sameSite = true
+			}
+		}
+		if !httpOnly || !sameSite || (https && !secure) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const maxCNAMEChain = 10
+
+type TakeoverFingerprint struct {
+	Service  string `yaml:"service"`
+	CNAME    string `yaml:"cname"`
+	Pattern  string `yaml:"pattern"`
+	NXDomain bool   `yaml:"nxdomain"`
+}
+
+// Fingerprints of unclaimed cloud resources, CNAME is matched as suffix.
+var defaultTakeoverFingerprints = []TakeoverFingerprint{
+	{Service: "AWS S3", CNAME: "amazonaws.com", Pattern: "NoSuchBucket|The specified bucket does not exist"},
+	{Service: "Heroku", CNAME: "herokuapp.com", Pattern: "No such app|There's nothing here, yet", NXDomain: true},
+	{Service: "Heroku", CNAME: "herokudns.com", Pattern: "No such app|There's nothing here, yet", NXDomain: true},
+	{Service: "Azure", CNAME: "azurewebsites.net", Pattern: "404 Web Site not found", NXDomain: true},
+	{Service: "Azure", CNAME: "cloudapp.net", NXDomain: true},
+	{Service: "Azure", CNAME: "cloudapp.azure.com", NXDomain: true},
+	{Service: "Azure", CNAME: "trafficmanager.net", NXDomain: true},
+	{Service: "Azure", CNAME: "blob.core.windows.net", Pattern: "The specified resource does not exist", NXDomain: true},
+	{Service: "GitHub Pages", CNAME: "github.io", Pattern: "There isn't a GitHub Pages site here"},
+	{Service: "Fastly", CNAME: "fastly.net", Pattern: "Fastly error: unknown domain"},
+	{Service: "Shopify", CNAME: "myshopify.com", Pattern: "Sorry, this shop is currently unavailable"},
+}
+
+type TakeoverResult struct {
+	Domain   string
+	CNAME    string
+	Chain    []string
+	Status   string
+	Risk     bool
+	Service  string
+	Reason   string
+	ErrorMsg string
+}
+
+type TakeoverResults map[string]TakeoverResult
+
+type takeoverMatcher struct {
+	TakeoverFingerprint
+	pattern *regexp.Regexp
+}
+
+type TakeoverChecker struct {
+	Domains []string
+	// Address family to fetch fingerprint, both means system default
+	IPProtocol string
+	matchers   []takeoverMatcher
+}
+
+func NewTakeoverChecker(domains []string, fingerprints []TakeoverFingerprint) *TakeoverChecker {
+	matchers := []takeoverMatcher{}
+	all := append([]TakeoverFingerprint{}, fingerprints...)
+	for _, fp := range append(all, defaultTakeoverFingerprints...) {
+		m := takeoverMatcher{TakeoverFingerprint: fp}
+		if fp.Pattern != "" {
+			pattern, err := regexp.Compile(fp.Pattern)
+			if err != nil {
+				log.Printf("TakeoverChecker Error: invalid pattern for %s: %v", fp.Service, err)
+				continue
+			}
+			m.pattern = pattern
+		}
+		matchers = append(matchers, m)
+	}
+	return &TakeoverChecker{
+		Domains:  domains,
+		matchers: matchers,
+	}
+}
+
+func (tc *TakeoverChecker) Check() TakeoverResults {
+	var (
+		lock sync.Mutex
+		wg   sync.WaitGroup
+		ret  TakeoverResults = make(TakeoverResults)
+	)
+	wg.Add(len(tc.Domains))
+	for _, item := range tc.Domains {
+		go func(domain string) {
+			tr := tc.CheckOneDomain(domain)
+			lock.Lock()
+			ret[domain] = tr
+			lock.Unlock()
+			if tr.Risk {
+				log.Printf("TakeoverChecker Warning: %s -> %s: %s", domain, tr.CNAME, tr.Reason)
+			}
+			wg.Done()
+		}(item)
+	}
+	wg.Wait()
+	return ret
+}
+
+func (tc *TakeoverChecker) CheckOneDomain(domain string) TakeoverResult {
+	ret := TakeoverResult{
+		Domain: domain,
+		Status: "Error",
+	}
+	chain, err := followCNAME(domain)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	ret.Chain = chain
+	if len(chain) == 0 {
+		// Not an alias, nothing can be taken over
+		ret.Status = "OK"
+		return ret
+	}
+	ret.CNAME = chain[len(chain)-1]
+	matchers := tc.matchChain(chain)
+
+	addrs, err := lookupHost(ret.CNAME)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			ret.Status = "OK"
+			ret.Risk = true
+			ret.Reason = "nxdomain"
+			for _, m := range matchers {
+				if m.NXDomain {
+					ret.Service = m.Service
+					break
+				}
+			}
+			return ret
+		}
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	ret.Status = "OK"
+	// NXDOMAIN is checked in all families, only fingerprint is fetched over family
+	addrs = filterFamily(addrs, singleFamily(tc.IPProtocol))
+	if len(matchers) == 0 || len(addrs) == 0 {
+		return ret
+	}
+	body, err := tc.fetchBody(domain, selectAddress(addrs))
+	if err != nil {
+		// Unreachable target is not a takeover evidence
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	for _, m := range matchers {
+		if m.pattern != nil && m.pattern.Match(body) {
+			ret.Risk = true
+			ret.Service = m.Service
+			ret.Reason = "fingerprint"
+			return ret
+		}
+	}
+	return ret
+}
+
+func (tc *TakeoverChecker) matchChain(chain []string) []takeoverMatcher {
+	ret := []takeoverMatcher{}
+	for _, m := range tc.matchers {
+		for _, name := range chain {
+			if m.CNAME == "" || name == m.CNAME || strings.HasSuffix(name, "."+m.CNAME) {
+				ret = append(ret, m)
+				break
+			}
+		}
+	}
+	return ret
+}
+
+// fetchBody returns page of domain served by raddr, redirects of the same
+// host such as HTTP to HTTPS are followed.
+func (tc *TakeoverChecker) fetchBody(domain, raddr string) ([]byte, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://%s/", domain), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Unclaimed resource is usually served with certificate of the provider,
+	// only the body is used so certificate is not verified
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	client := &http.Client{
+		Transport: newPinnedTransport(raddr, tlsConfig, getGlobalDialer()),
+		Timeout:   10 * time.Second,
+		// Fingerprint pages are served by the cloud provider directly, other
+		// hosts are not requested
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !strings.EqualFold(req.URL.Hostname(), domain) {
+				return http.ErrUseLastResponse
+			}
+			return checkRedirectLoop(req, via)
+		},
+	}
+	defer client.CloseIdleConnections()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// followCNAME returns CNAME chain of domain, empty if domain is not an alias.
+func followCNAME(domain string) ([]string, error) {
+	chain := []string{}
+	name := domain
+	for i := 0; i < maxCNAMEChain; i++ {
+		resp, err := queryDNS(name, dnsmessage.TypeCNAME)
+		if err != nil {
+			return chain, err
+		}
+		switch resp.RCode {
+		case dnsmessage.RCodeSuccess, dnsmessage.RCodeNameError:
+		default:
+			return chain, fmt.Errorf("Lookup CNAME of %s failed: %v", name, resp.RCode)
+		}
+		target := ""
+		for _, answer := range resp.Answers {
+			body, ok := answer.Body.(*dnsmessage.CNAMEResource)
+			if ok && strings.EqualFold(answer.Header.Name.String(), name+".") {
+				target = strings.TrimSuffix(strings.ToLower(body.CNAME.String()), ".")
+				break
+			}
+		}
+		if target == "" {
+			return chain, nil
+		}
+		chain = append(chain, target)
+		name = target
+	}
+	return chain, fmt.Errorf("CNAME chain of %s is too long", domain)
+}
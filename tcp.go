@@ -0,0 +1,181 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const tcpTimeout = 10 * time.Second
+
+type TCPResult struct {
+	Domain         string
+	Host           string
+	Port           int
+	IPFamily       string
+	Address        string
+	Status         string
+	ErrorMsg       string
+	ConnectLatency time.Duration
+	CertExpireDays int
+	HasCertificate bool
+}
+
+type TCPParams struct {
+	Domain   string
+	IPFamily string
+	Config   *TCPConfig
+}
+
+type TCPResults map[string]TCPResult
+
+type TCPChecker struct {
+	Domains []TCPConfig
+	// Default ip_protocol of checks
+	IPProtocol string
+}
+
+func NewTCPChecker(domains []TCPConfig) *TCPChecker {
+	return &TCPChecker{
+		Domains: domains,
+	}
+}
+
+func (tc *TCPChecker) Check() TCPResults {
+	var (
+		lock sync.Mutex
+		wg   sync.WaitGroup
+		ret  TCPResults = make(TCPResults)
+	)
+	targets := []*TCPParams{}
+	for i := range tc.Domains {
+		cfg := &tc.Domains[i]
+		protocol := cfg.IPProtocol
+		if protocol == "" {
+			protocol = tc.IPProtocol
+		}
+		domains := cfg.Domains
+		if len(domains) == 0 {
+			domains = []string{cfg.Host}
+		}
+		for _, domain := range domains {
+			for _, family := range ipFamilies(protocol) {
+				targets = append(targets, &TCPParams{
+					Domain:   domain,
+					IPFamily: family,
+					Config:   cfg,
+				})
+			}
+		}
+	}
+	wg.Add(len(targets))
+	for _, item := range targets {
+		go func(params *TCPParams) {
+			tr := tc.CheckOneDomain(params)
+			lock.Lock()
+			key := fmt.Sprintf("%s:%d @ %s", params.Config.Host, params.Config.Port, params.Domain)
+			ret[familyKey(key, params.IPFamily)] = tr
+			lock.Unlock()
+			if tr.ErrorMsg != "" {
+				log.Printf("TCPChecker Error: %s: %s:%d -> %v", params.Domain, params.Config.Host, params.Config.Port, tr.ErrorMsg)
+			}
+			wg.Done()
+		}(item)
+	}
+	wg.Wait()
+	return ret
+}
+
+func (tc *TCPChecker) CheckOneDomain(params *TCPParams) TCPResult {
+	cfg := params.Config
+	ret := TCPResult{
+		Domain:   params.Domain,
+		Host:     cfg.Host,
+		Port:     cfg.Port,
+		IPFamily: params.IPFamily,
+		Status:   "Error",
+	}
+	addrs, err := lookupHostFamily(params.Domain, params.IPFamily)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	if len(addrs) == 0 {
+		ret.ErrorMsg = "Domain has no IP addresses"
+		return ret
+	}
+	ret.Address = selectAddress(addrs)
+	dialer, err := getDialer(cfg.Proxy)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+
+	start := time.Now()
+	conn, err := dialTimeoutWith(dialer, tcpNetwork(params.IPFamily), net.JoinHostPort(ret.Address, strconv.Itoa(cfg.Port)), tcpTimeout)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	ret.ConnectLatency = time.Since(start)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(tcpTimeout))
+
+	if cfg.TLS {
+		tlsConn := tls.Client(conn, &tls.Config{
+			ServerName:         cfg.Host,
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+		})
+		if err = tlsConn.Handshake(); err != nil {
+			ret.ErrorMsg = fmt.Sprintf("%v", err)
+			return ret
+		}
+		if cert, err := getLeafCertificate(tlsConn.ConnectionState()); err == nil {
+			ret.HasCertificate = true
+			ret.CertExpireDays = expireDays(cert.NotAfter)
+		}
+		conn = tlsConn
+	}
+
+	if cfg.Send != "" {
+		if _, err = conn.Write([]byte(cfg.Send)); err != nil {
+			ret.ErrorMsg = fmt.Sprintf("%v", err)
+			return ret
+		}
+	}
+	if cfg.Expect != "" {
+		if err = expectResponse(conn, cfg.Expect); err != nil {
+			ret.ErrorMsg = fmt.Sprintf("%v", err)
+			return ret
+		}
+	}
+	ret.Status = "OK"
+	log.Println("[INFO] TCP", net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)), "@", params.Domain, "Connect Latency", ret.ConnectLatency)
+	return ret
+}
+
+// expectResponse read from conn until data match pattern, timeout or EOF.
+func expectResponse(conn net.Conn, pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	data := []byte{}
+	buf := make([]byte, 4096)
+	for len(data) < 1<<20 {
+		n, err := conn.Read(buf)
+		data = append(data, buf[:n]...)
+		if re.Match(data) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Response not match %q: %v", pattern, err)
+		}
+	}
+	return fmt.Errorf("Response not match %q", pattern)
+}
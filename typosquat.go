@@ -0,0 +1,277 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	typosquatConcurrency = 16
+	// Permutations queried by whois in a collection of each domain
+	defaultTyposquatWhoisLimit = 20
+)
+
+// ASCII characters that look alike.
+var asciiHomoglyphs = map[rune][]string{
+	'a': {"4"},
+	'b': {"d", "lb"},
+	'd': {"b", "cl"},
+	'e': {"3"},
+	'g': {"q", "9"},
+	'i': {"1", "l"},
+	'l': {"1", "i"},
+	'm': {"rn", "nn"},
+	'n': {"m", "r"},
+	'o': {"0"},
+	'q': {"g"},
+	's': {"5"},
+	'u': {"v"},
+	'v': {"u"},
+	'w': {"vv"},
+	'z': {"2"},
+}
+
+// Unicode characters that look like ASCII ones, result in IDN lookalikes.
+var idnHomoglyphs = map[rune][]string{
+	'a': {"а", "ạ", "á"},
+	'c': {"с", "ç"},
+	'e': {"е", "é", "ė"},
+	'h': {"һ"},
+	'i': {"і", "í"},
+	'j': {"ј"},
+	'k': {"κ"},
+	'l': {"ӏ"},
+	'n': {"ո"},
+	'o': {"о", "ο", "ó"},
+	'p': {"р"},
+	's': {"ѕ"},
+	'u': {"υ", "ú"},
+	'x': {"х"},
+	'y': {"у", "ý"},
+}
+
+var defaultTyposquatTLDs = []string{"com", "net", "org", "info", "biz", "co", "io", "cn", "app", "xyz", "top", "online", "site"}
+
+type TyposquatHit struct {
+	Domain string
+	Kind   string
+}
+
+type TyposquatResult struct {
+	Domain       string
+	Status       string
+	Permutations int
+	Hits         []TyposquatHit
+}
+
+type TyposquatResults map[string]TyposquatResult
+
+// typosquatWhoisCache keeps whois results of permutations across collections,
+// so only a few permutations are queried by whois in each collection.
+type typosquatWhoisCache struct {
+	// Next permutation to query of each domain
+	cursors map[string]int
+	// Whois registered state of unresolved permutations of each domain
+	registered map[string]map[string]bool
+	lock       sync.Mutex
+}
+
+func newTyposquatWhoisCache() *typosquatWhoisCache {
+	return &typosquatWhoisCache{
+		cursors:    make(map[string]int),
+		registered: make(map[string]map[string]bool),
+	}
+}
+
+type TyposquatChecker struct {
+	Domains    []TyposquatConfig
+	WhoisCache *typosquatWhoisCache
+	// Address family to connect whois servers, permutations are resolved
+	// in all families because any address means it is registered
+	IPProtocol string
+}
+
+func NewTyposquatChecker(domains []TyposquatConfig) *TyposquatChecker {
+	return &TyposquatChecker{
+		Domains:    domains,
+		WhoisCache: newTyposquatWhoisCache(),
+	}
+}
+
+func (tc *TyposquatChecker) Check() TyposquatResults {
+	ret := make(TyposquatResults)
+	// Domains are checked one by one, permutations are checked concurrently
+	for _, cfg := range tc.Domains {
+		ret[cfg.Domain] = tc.CheckOneDomain(cfg)
+	}
+	return ret
+}
+
+func (tc *TyposquatChecker) CheckOneDomain(cfg TyposquatConfig) TyposquatResult {
+	ret := TyposquatResult{
+		Domain: cfg.Domain,
+		Status: "Error",
+		Hits:   []TyposquatHit{},
+	}
+	perms := GeneratePermutations(cfg.Domain, cfg.TLDs)
+	ret.Permutations = len(perms)
+	if len(perms) == 0 {
+		return ret
+	}
+	var (
+		lock       sync.Mutex
+		wg         sync.WaitGroup
+		sem        = make(chan struct{}, typosquatConcurrency)
+		unresolved = []string{}
+	)
+	for name, kind := range perms {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(domain, kind string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			addrs, err := lookupHost(domain)
+			lock.Lock()
+			if err == nil && len(addrs) > 0 {
+				ret.Hits = append(ret.Hits, TyposquatHit{Domain: domain, Kind: kind})
+			} else {
+				unresolved = append(unresolved, domain)
+			}
+			lock.Unlock()
+		}(name, kind)
+	}
+	wg.Wait()
+	if cfg.Whois {
+		for _, domain := range tc.checkWhois(cfg, unresolved) {
+			ret.Hits = append(ret.Hits, TyposquatHit{Domain: domain, Kind: perms[domain]})
+		}
+	}
+	sort.Slice(ret.Hits, func(i, j int) bool {
+		return ret.Hits[i].Domain < ret.Hits[j].Domain
+	})
+	ret.Status = "OK"
+	log.Println("[INFO] Typosquat", cfg.Domain, "Registered", len(ret.Hits), "of", ret.Permutations, "Permutations")
+	return ret
+}
+
+// checkWhois query whois of at most whois_limit unresolved permutations one by
+// one, the next ones are queried in the next collection. Returns permutations
+// registered by latest whois results.
+func (tc *TyposquatChecker) checkWhois(cfg TyposquatConfig, unresolved []string) []string {
+	sort.Strings(unresolved)
+	cache := tc.WhoisCache
+	cache.lock.Lock()
+	previous := cache.registered[cfg.Domain]
+	start := cache.cursors[cfg.Domain]
+	cache.lock.Unlock()
+
+	// Drop permutations that are resolved or no longer generated
+	registered := make(map[string]bool)
+	for _, domain := range unresolved {
+		if state, ok := previous[domain]; ok {
+			registered[domain] = state
+		}
+	}
+	count := len(unresolved)
+	if limit := cfg.GetWhoisLimit(); count > limit {
+		count = limit
+	}
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			// Avoid rate limit of whois servers, same as WhoisChecker
+			time.Sleep(1 * time.Second)
+		}
+		domain := unresolved[(start+i)%len(unresolved)]
+		registered[domain] = isWhoisRegistered(domain, singleFamily(tc.IPProtocol))
+	}
+
+	cache.lock.Lock()
+	cache.registered[cfg.Domain] = registered
+	cache.cursors[cfg.Domain] = start + count
+	cache.lock.Unlock()
+
+	ret := []string{}
+	for domain, ok := range registered {
+		if ok {
+			ret = append(ret, domain)
+		}
+	}
+	return ret
+}
+
+func isWhoisRegistered(domain, family string) bool {
+	info, err := GetWhoisTimeout(domain, family, 5*time.Second)
+	if err != nil {
+		return false
+	}
+	et, ok := decodeWhoisExpire(info)
+	return ok && !et.IsZero()
+}
+
+// GeneratePermutations returns lookalike domains of domain in ASCII form
+// with the kind of permutation.
+func GeneratePermutations(domain string, tlds []string) map[string]string {
+	ret := make(map[string]string)
+	// Permutations are made of registrable domain, so subdomains such as
+	// www.example.com result in lookalikes of example.com
+	domain, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(strings.TrimSuffix(domain, ".")))
+	if err != nil {
+		return ret
+	}
+	idx := strings.Index(domain, ".")
+	name, tld := domain[:idx], domain[idx+1:]
+	add := func(label, suffix, kind string) {
+		candidate, err := idna.Lookup.ToASCII(label + "." + suffix)
+		if err != nil || candidate == domain || label == "" {
+			return
+		}
+		if _, ok := ret[candidate]; !ok {
+			ret[candidate] = kind
+		}
+	}
+
+	runes := []rune(name)
+	for i := range runes {
+		// Omission
+		add(string(runes[:i])+string(runes[i+1:]), tld, "omission")
+		// Transposition
+		if i+1 < len(runes) && runes[i] != runes[i+1] {
+			swapped := append([]rune{}, runes...)
+			swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+			add(string(swapped), tld, "transposition")
+		}
+		// Homoglyphs
+		for _, glyph := range asciiHomoglyphs[runes[i]] {
+			add(string(runes[:i])+glyph+string(runes[i+1:]), tld, "homoglyph")
+		}
+		for _, glyph := range idnHomoglyphs[runes[i]] {
+			add(string(runes[:i])+glyph+string(runes[i+1:]), tld, "idn")
+		}
+		// Bitsquatting
+		for bit := uint(0); bit < 8; bit++ {
+			flipped := runes[i] ^ (1 << bit)
+			if (flipped >= 'a' && flipped <= 'z') || (flipped >= '0' && flipped <= '9') || flipped == '-' {
+				add(string(runes[:i])+string(flipped)+string(runes[i+1:]), tld, "bitsquatting")
+			}
+		}
+	}
+
+	// TLD swaps
+	if len(tlds) == 0 {
+		tlds = defaultTyposquatTLDs
+	}
+	for _, swap := range tlds {
+		if swap != tld {
+			add(name, swap, "tld")
+		}
+	}
+	return ret
+}
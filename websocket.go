@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	websocketTimeout = 10 * time.Second
+	// GUID used to compute Sec-WebSocket-Accept, see RFC 6455 section 1.3
+	websocketGUID       = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	websocketMaxMessage = 1 << 20
+
+	websocketOpContinuation = 0x0
+	websocketOpText         = 0x1
+	websocketOpBinary       = 0x2
+	websocketOpClose        = 0x8
+	websocketOpPing         = 0x9
+	websocketOpPong         = 0xa
+)
+
+type WebSocketResult struct {
+	Domain           string
+	Host             string
+	Path             string
+	IPFamily         string
+	Address          string
+	Status           string
+	ErrorMsg         string
+	StatusCode       int
+	HandshakeLatency time.Duration
+	RTT              time.Duration
+}
+
+type WebSocketParams struct {
+	Domain   string
+	IPFamily string
+	Config   *WebSocketConfig
+}
+
+type WebSocketResults map[string]WebSocketResult
+
+type WebSocketChecker struct {
+	Domains []WebSocketConfig
+	// Default ip_protocol of checks
+	IPProtocol string
+}
+
+func NewWebSocketChecker(domains []WebSocketConfig) *WebSocketChecker {
+	return &WebSocketChecker{
+		Domains: domains,
+	}
+}
+
+func (wc *WebSocketChecker) Check() WebSocketResults {
+	var (
+		lock sync.Mutex
+		wg   sync.WaitGroup
+		ret  WebSocketResults = make(WebSocketResults)
+	)
+	targets := []*WebSocketParams{}
+	for i := range wc.Domains {
+		cfg := &wc.Domains[i]
+		protocol := cfg.IPProtocol
+		if protocol == "" {
+			protocol = wc.IPProtocol
+		}
+		domains := cfg.Domains
+		if len(domains) == 0 {
+			domains = []string{hostname(cfg.Host)}
+		}
+		for _, domain := range domains {
+			for _, family := range ipFamilies(protocol) {
+				targets = append(targets, &WebSocketParams{
+					Domain:   domain,
+					IPFamily: family,
+					Config:   cfg,
+				})
+			}
+		}
+	}
+	wg.Add(len(targets))
+	for _, item := range targets {
+		go func(params *WebSocketParams) {
+			wr := wc.CheckOneDomain(params)
+			lock.Lock()
+			key := fmt.Sprintf("%s @ %s%s", params.Domain, params.Config.Host, params.Config.Path)
+			ret[familyKey(key, params.IPFamily)] = wr
+			lock.Unlock()
+			if wr.ErrorMsg != "" {
+				log.Printf("WebSocketChecker Error: %s: %s%s -> %v", params.Domain, params.Config.Host, params.Config.Path, wr.ErrorMsg)
+			}
+			wg.Done()
+		}(item)
+	}
+	wg.Wait()
+	return ret
+}
+
+func (wc *WebSocketChecker) CheckOneDomain(params *WebSocketParams) WebSocketResult {
+	cfg := params.Config
+	ret := WebSocketResult{
+		Domain:   params.Domain,
+		Host:     cfg.Host,
+		Path:     cfg.Path,
+		IPFamily: params.IPFamily,
+		Status:   "Error",
+	}
+	addrs, err := lookupHostFamily(params.Domain, params.IPFamily)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	if len(addrs) == 0 {
+		ret.ErrorMsg = "Domain has no IP addresses"
+		return ret
+	}
+	ret.Address = selectAddress(addrs)
+	dialer, err := getDialer(cfg.Proxy)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+
+	start := time.Now()
+	conn, err := dialTimeoutWith(dialer, tcpNetwork(params.IPFamily), net.JoinHostPort(ret.Address, cfg.GetPort()), websocketTimeout)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(websocketTimeout))
+	if cfg.TLS {
+		tlsConfig, err := newTLSConfig(cfg.InsecureSkipVerify, cfg.CAFile)
+		if err != nil {
+			ret.ErrorMsg = fmt.Sprintf("%v", err)
+			return ret
+		}
+		tlsConfig.ServerName = hostname(cfg.Host)
+		// Upgrade is not supported by HTTP/2
+		tlsConfig.NextProtos = []string{"http/1.1"}
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err = tlsConn.Handshake(); err != nil {
+			ret.ErrorMsg = fmt.Sprintf("%v", err)
+			return ret
+		}
+		conn = tlsConn
+	}
+	br := bufio.NewReader(conn)
+	ret.StatusCode, err = websocketHandshake(conn, br, cfg)
+	if err != nil {
+		ret.ErrorMsg = fmt.Sprintf("%v", err)
+		return ret
+	}
+	ret.HandshakeLatency = time.Since(start)
+
+	if cfg.Send != "" || cfg.Expect != "" {
+		start = time.Now()
+		if err = websocketEcho(conn, br, cfg); err != nil {
+			ret.ErrorMsg = fmt.Sprintf("%v", err)
+			return ret
+		}
+		ret.RTT = time.Since(start)
+	}
+	// Close normally, error is ignored because the check is finished
+	writeWebSocketFrame(conn, websocketOpClose, []byte{0x03, 0xe8})
+	ret.Status = "OK"
+	log.Println("[INFO] WebSocket", cfg.Host+cfg.Path, "@", params.Domain, "Handshake", ret.HandshakeLatency, "RTT", ret.RTT)
+	return ret
+}
+
+// websocketHandshake send upgrade request and verify the response, returns
+// response status code.
+func websocketHandshake(conn net.Conn, br *bufio.Reader, cfg *WebSocketConfig) (int, error) {
+	scheme := "ws"
+	if cfg.TLS {
+		scheme = "wss"
+	}
+	path := cfg.Path
+	if path == "" {
+		path = "/"
+	}
+	u, err := url.Parse(fmt.Sprintf("%s://%s%s", scheme, cfg.Host, path))
+	if err != nil {
+		return 0, err
+	}
+	nonce := make([]byte, 16)
+	if _, err = rand.Read(nonce); err != nil {
+		return 0, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+	req := &http.Request{
+		Method: "GET",
+		URL:    u,
+		Host:   cfg.Host,
+		Header: make(http.Header),
+	}
+	for name, value := range cfg.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err = req.Write(conn); err != nil {
+		return 0, err
+	}
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		resp.Body.Close()
+		return resp.StatusCode, fmt.Errorf("WebSocket handshake status not equals to 101, %v", resp.StatusCode)
+	}
+	h := sha1.Sum([]byte(key + websocketGUID))
+	if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(h[:]) {
+		return resp.StatusCode, fmt.Errorf("Invalid Sec-WebSocket-Accept header")
+	}
+	return resp.StatusCode, nil
+}
+
+// websocketEcho send message and wait for a reply matching expect pattern.
+func websocketEcho(conn net.Conn, br *bufio.Reader, cfg *WebSocketConfig) error {
+	var re *regexp.Regexp
+	if cfg.Expect != "" {
+		var err error
+		if re, err = regexp.Compile(cfg.Expect); err != nil {
+			return err
+		}
+	}
+	if cfg.Send != "" {
+		if err := writeWebSocketFrame(conn, websocketOpText, []byte(cfg.Send)); err != nil {
+			return err
+		}
+	}
+	if re == nil {
+		return nil
+	}
+	for {
+		msg, err := readWebSocketMessage(conn, br)
+		if err != nil {
+			return fmt.Errorf("Reply not match %q: %v", cfg.Expect, err)
+		}
+		if re.Match(msg) {
+			return nil
+		}
+	}
+}
+
+// writeWebSocketFrame write a masked frame, client frames must be masked.
+func writeWebSocketFrame(w io.Writer, opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return err
+	}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := w.Write(frame)
+	return err
+}
+
+// readWebSocketMessage returns payload of next text or binary message, control
+// frames are handled and skipped.
+func readWebSocketMessage(conn net.Conn, br *bufio.Reader) ([]byte, error) {
+	msg := []byte{}
+	for {
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(br, header); err != nil {
+			return nil, err
+		}
+		fin := header[0]&0x80 != 0
+		opcode := header[0] & 0x0f
+		size := uint64(header[1] & 0x7f)
+		switch size {
+		case 126:
+			ext := make([]byte, 2)
+			if _, err := io.ReadFull(br, ext); err != nil {
+				return nil, err
+			}
+			size = uint64(binary.BigEndian.Uint16(ext))
+		case 127:
+			ext := make([]byte, 8)
+			if _, err := io.ReadFull(br, ext); err != nil {
+				return nil, err
+			}
+			size = binary.BigEndian.Uint64(ext)
+		}
+		if size > websocketMaxMessage || uint64(len(msg))+size > websocketMaxMessage {
+			return nil, fmt.Errorf("WebSocket message too large")
+		}
+		var mask []byte
+		if header[1]&0x80 != 0 {
+			mask = make([]byte, 4)
+			if _, err := io.ReadFull(br, mask); err != nil {
+				return nil, err
+			}
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return nil, err
+		}
+		for i := range mask {
+			for j := i; j < len(payload); j += 4 {
+				payload[j] ^= mask[i]
+			}
+		}
+		switch opcode {
+		case websocketOpClose:
+			return nil, fmt.Errorf("WebSocket closed by server")
+		case websocketOpPing:
+			if err := writeWebSocketFrame(conn, websocketOpPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case websocketOpPong:
+			continue
+		case websocketOpText, websocketOpBinary, websocketOpContinuation:
+			msg = append(msg, payload...)
+		default:
+			return nil, fmt.Errorf("Unknown WebSocket opcode %d", opcode)
+		}
+		if fin {
+			return msg, nil
+		}
+	}
+}
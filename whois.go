@@ -5,12 +5,15 @@ import (
 	"io/ioutil"
 	"log"
 	"net"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
-func GetWhoisTimeout(domain string, timeout time.Duration) (string, error) {
+// GetWhoisTimeout query whois of domain over family, empty family means
+// system default.
+func GetWhoisTimeout(domain, family string, timeout time.Duration) (string, error) {
 	parts := strings.Split(domain, ".")
 	if len(parts) < 2 {
 		err := fmt.Errorf("Domain(%s) name is wrong!", domain)
@@ -23,19 +26,19 @@ func GetWhoisTimeout(domain string, timeout time.Duration) (string, error) {
 	if !ok {
 		server = defaultServer
 	}
-	ret, err := GetWhoisWithServerTimeout(domain, server, timeout)
+	ret, err := GetWhoisWithServerTimeout(domain, server, family, timeout)
 	data := strings.TrimSpace(ret)
 	if err == nil && data != "" {
 		return ret, nil
 	}
 	if server != defaultServer {
-		return GetWhoisWithServerTimeout(domain, defaultServer, timeout)
+		return GetWhoisWithServerTimeout(domain, defaultServer, family, timeout)
 	}
 	return ret, err
 }
 
-func GetWhoisWithServerTimeout(domain, server string, timeout time.Duration) (string, error) {
-	conn, err := net.DialTimeout("tcp", net.JoinHostPort(server, "43"), timeout)
+func GetWhoisWithServerTimeout(domain, server, family string, timeout time.Duration) (string, error) {
+	conn, err := dialTimeoutWith(getGlobalDialer(), tcpNetwork(family), net.JoinHostPort(server, "43"), timeout)
 	if err != nil {
 		return "", err
 	}
@@ -49,18 +52,93 @@ func GetWhoisWithServerTimeout(domain, server string, timeout time.Duration) (st
 	return string(buf), nil
 }
 
+// EPP status codes of domain, see RFC 5731 section 2.3 and RFC 3915 section 3.
+var eppStatusCodes = []string{
+	"ok",
+	"inactive",
+	"pendingCreate",
+	"pendingDelete",
+	"pendingRenew",
+	"pendingTransfer",
+	"pendingUpdate",
+	"pendingRestore",
+	"clientDeleteProhibited",
+	"clientHold",
+	"clientRenewProhibited",
+	"clientTransferProhibited",
+	"clientUpdateProhibited",
+	"serverDeleteProhibited",
+	"serverHold",
+	"serverRenewProhibited",
+	"serverTransferProhibited",
+	"serverUpdateProhibited",
+	"addPeriod",
+	"autoRenewPeriod",
+	"renewPeriod",
+	"transferPeriod",
+	"redemptionPeriod",
+}
+
+// Whois field names of domain status in different registries
+var whoisStatusKeys = []string{"domain status", "status"}
+
+// eppStatusNames maps lower case status without spaces to status code.
+var eppStatusNames = func() map[string]string {
+	ret := map[string]string{
+		// Some registries show ok as active
+		"active": "ok",
+	}
+	for _, code := range eppStatusCodes {
+		ret[strings.ToLower(code)] = code
+	}
+	return ret
+}()
+
 type WhoisResult struct {
 	Domain     string
 	Status     string
 	ErrorMsg   string
 	ExpireAt   time.Time
 	ExpireDays int
+	// EPP status codes of domain
+	EPPStatuses []string
+	// Registration details, empty if not shown by whois server
+	Registrar       string
+	RegistrarIANAID string
+	NameServers     []string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DNSSEC          string
+}
+
+func (wr WhoisResult) HasEPPStatus(codes ...string) bool {
+	for _, status := range wr.EPPStatuses {
+		for _, code := range codes {
+			if status == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TransferLocked returns true if domain cannot be transferred.
+func (wr WhoisResult) TransferLocked() bool {
+	return wr.HasEPPStatus("clientTransferProhibited", "serverTransferProhibited")
+}
+
+// InGraceOrRedemption returns true if domain is expired and waiting for
+// renew, restore or delete.
+func (wr WhoisResult) InGraceOrRedemption() bool {
+	return wr.HasEPPStatus("autoRenewPeriod", "redemptionPeriod", "pendingRestore", "pendingDelete")
 }
 
 type WhoisResults map[string]WhoisResult
 
 type WhoisChecker struct {
 	Domains []string
+	// Address family to connect whois servers, both means system default
+	IPProtocol string
 }
 
 func NewWhoisChecker(domains []string) *WhoisChecker {
@@ -96,7 +174,7 @@ func (wc *WhoisChecker) CheckOneDomain(domain string) WhoisResult {
 		err   error
 	)
 	for i := 1; i < 4; i++ {
-		whois, err = GetWhoisTimeout(domain, 5*time.Second)
+		whois, err = GetWhoisTimeout(domain, singleFamily(wc.IPProtocol), 5*time.Second)
 		if err == nil {
 			break
 		}
@@ -106,15 +184,136 @@ func (wc *WhoisChecker) CheckOneDomain(domain string) WhoisResult {
 		ret.ErrorMsg = fmt.Sprintf("%s", err)
 		return ret
 	}
-	et, days := wc.decodeWhoisInfo(whois)
-	log.Println("[INFO] Whois", domain, "Expire After", days, "Days,", et)
+	wc.decodeWhoisInfo(whois, &ret)
+	log.Println("[INFO] Whois", domain, "Expire After", ret.ExpireDays, "Days,", ret.ExpireAt, "Status", ret.EPPStatuses)
 	ret.Status = "OK"
+	return ret
+}
+
+func (wc *WhoisChecker) decodeWhoisInfo(info string, ret *WhoisResult) {
+	ret.EPPStatuses = decodeWhoisEPPStatuses(info)
+	decodeWhoisDetails(info, ret)
+	et, ok := decodeWhoisExpire(info)
+	if !ok {
+		log.Println("----Error Cannot Parse Whois Info----")
+		log.Println(info)
+		log.Println("-------------------------------------")
+		return
+	}
 	ret.ExpireAt = et
-	ret.ExpireDays = days
+	ret.ExpireDays = expireDays(et)
+}
+
+// decodeWhoisEPPStatuses returns EPP status codes in status lines such as
+// "Domain Status: clientTransferProhibited https://icann.org/epp#clientTransferProhibited".
+func decodeWhoisEPPStatuses(info string) []string {
+	ret := []string{}
+	seen := make(map[string]bool)
+	for _, rline := range strings.Split(info, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(rline), ":", 2)
+		if len(parts) != 2 || !matchWhoisKey(strings.ToLower(strings.TrimSpace(parts[0])), whoisStatusKeys) {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		// Remove trailing URL or description
+		if idx := strings.Index(value, "http"); idx > 0 {
+			value = value[:idx]
+		}
+		if idx := strings.Index(value, "("); idx > 0 {
+			value = value[:idx]
+		}
+		// Some registries put multiple statuses in one line
+		for _, status := range strings.Split(value, ",") {
+			name := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(status), " ", ""))
+			if code, ok := eppStatusNames[name]; ok && !seen[code] {
+				seen[code] = true
+				ret = append(ret, code)
+			}
+		}
+	}
 	return ret
 }
 
-func (wc *WhoisChecker) decodeWhoisInfo(info string) (time.Time, int) {
+// Whois field names of registration details in different registries
+var (
+	whoisRegistrarKeys  = []string{"registrar", "sponsoring registrar", "registrar name"}
+	whoisIANAIDKeys     = []string{"registrar iana id"}
+	whoisNameServerKeys = []string{"name server", "name servers", "nameserver", "nserver"}
+	whoisCreatedKeys    = []string{"creation date", "created on", "created", "registration time", "registered on"}
+	whoisUpdatedKeys    = []string{"updated date", "last updated on", "last updated", "last modified", "changed"}
+	whoisDNSSECKeys     = []string{"dnssec"}
+	whoisDateLayouts    = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02", "02-Jan-2006", "2006/01/02", "2006.01.02"}
+)
+
+func matchWhoisKey(key string, keys []string) bool {
+	for _, k := range keys {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeWhoisDetails decode registrar, name servers, dates and DNSSEC status
+// in "Key: Value" lines, the first value of each key is used.
+func decodeWhoisDetails(info string, ret *WhoisResult) {
+	seen := make(map[string]bool)
+	for _, rline := range strings.Split(info, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(rline), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+		if value == "" {
+			continue
+		}
+		switch {
+		case matchWhoisKey(key, whoisNameServerKeys):
+			// Value may be followed by addresses of name server
+			ns := strings.TrimSuffix(strings.ToLower(strings.Fields(value)[0]), ".")
+			if !seen["ns "+ns] {
+				seen["ns "+ns] = true
+				ret.NameServers = append(ret.NameServers, ns)
+			}
+			continue
+		case seen[key]:
+			continue
+		case matchWhoisKey(key, whoisRegistrarKeys):
+			ret.Registrar = value
+		case matchWhoisKey(key, whoisIANAIDKeys):
+			ret.RegistrarIANAID = value
+		case matchWhoisKey(key, whoisCreatedKeys):
+			ret.CreatedAt = parseWhoisDate(value)
+		case matchWhoisKey(key, whoisUpdatedKeys):
+			ret.UpdatedAt = parseWhoisDate(value)
+		case matchWhoisKey(key, whoisDNSSECKeys):
+			ret.DNSSEC = value
+		}
+		seen[key] = true
+	}
+	sort.Strings(ret.NameServers)
+}
+
+// parseWhoisDate parse date in common whois formats, zero time if failed.
+func parseWhoisDate(date string) time.Time {
+	candidates := []string{date}
+	if fields := strings.Fields(date); len(fields) > 1 {
+		// Remove trailing time zone name such as "(JST)"
+		candidates = append(candidates, strings.Join(fields[:2], " "), fields[0])
+	}
+	for _, candidate := range candidates {
+		for _, layout := range whoisDateLayouts {
+			if t, err := time.Parse(layout, candidate); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// decodeWhoisExpire returns expire time in whois info, false if no expire line found.
+func decodeWhoisExpire(info string) (time.Time, bool) {
 	for _, rline := range strings.Split(info, "\n") {
 		if strings.Contains(rline, "Expir") {
 			line := strings.TrimSpace(rline)
@@ -123,25 +322,18 @@ func (wc *WhoisChecker) decodeWhoisInfo(info string) (time.Time, int) {
 				expireDate := strings.TrimSpace(parts[1])
 				expireDate = strings.ReplaceAll(expireDate, "T", " ")
 				eparts := strings.Split(strings.ToUpper(expireDate), "Z")
-				et := parseDateStr(eparts[0])
-				days := int(et.Sub(time.Now()).Hours() / 24)
-				return et, days
+				return parseDateStr(eparts[0]), true
 			}
 		} else if strings.Contains(rline, "有効期限") {
 			line := strings.TrimSpace(rline)
 			parts := strings.Split(line, "]")
 			if len(parts) == 2 {
 				dateStr := strings.TrimSpace(parts[1])
-				et := parseJPDateStr(dateStr)
-				days := int(et.Sub(time.Now()).Hours() / 24)
-				return et, days
+				return parseJPDateStr(dateStr), true
 			}
 		}
 	}
-	log.Println("----Error Cannot Parse Whois Info----")
-	log.Println(info)
-	log.Println("-------------------------------------")
-	return time.Time{}, 0
+	return time.Time{}, false
 }
 
 func parseDateStr(date string) time.Time {